@@ -1,6 +1,14 @@
 package main
 
 // Generates sudoku puzzles.
+//
+// Running:
+//    Keep printing puzzles with fewer clues than the last:
+//        ./generator
+//    Sudoku X puzzles, where the diagonals are houses too:
+//        ./generator -x
+//    Print steps done:
+//        ./generator -v
 
 import (
 	"fmt"
@@ -12,7 +20,15 @@ import (
 )
 
 func main() {
-	verbose := len(os.Args) > 1 && os.Args[1] == "-v"
+	verbose, diagonals := false, false
+	for _, arg := range os.Args[1:] {
+		switch arg {
+		case "-v":
+			verbose = true
+		case "-x":
+			diagonals = true
+		}
+	}
 	if !verbose {
 		log.SetFlags(0)
 		log.SetOutput(ioutil.Discard)
@@ -24,14 +40,22 @@ func main() {
 	for {
 		i++
 		rand.Seed(i)
-		s, e := sodacouplib.GenerateProblem()
+		s, e := sodacouplib.GenerateProblemWithVariant(sodacouplib.ClassicLayout, sodacouplib.Variant{Diagonals: diagonals})
 		if e != nil {
 			printFatal("error:%s", e)
 		}
 
 		c := s.SetCount()
 		if c < m {
-			fmt.Printf("%d seed puzzle (%d filled):\n", i, c)
+			grade, e := sodacouplib.Grade(s)
+			if e != nil {
+				printFatal("error:%s", e)
+			}
+			minimal, e := sodacouplib.IsMinimal(s)
+			if e != nil {
+				printFatal("error:%s", e)
+			}
+			fmt.Printf("%d seed puzzle (%d filled, %s, minimal %t):\n", i, c, grade, minimal)
 			fmt.Println(s)
 			m = c
 		}
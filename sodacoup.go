@@ -35,12 +35,21 @@ func main() {
 
 	sud, err := sodacouplib.NewSudokuSquare(problem)
 	if err != nil {
-		printFatal("Problem text doesn't look like a valid sudoku problem")
+		printFatal("error: %s", err)
 	}
 	fmt.Println("PROBLEM:")
 	fmt.Println(sud)
 
-	err = sud.Solve()
+	warnIfNotUnique(sud)
+
+	grade, err := sodacouplib.Grade(sud)
+	if err != nil {
+		printFatal("error: %s", err)
+	}
+	fmt.Println("GRADE:", grade)
+	fmt.Println()
+
+	_, err = sud.Solve(sodacouplib.SolveOptions{})
 	if err != nil {
 		printFatal("error: %s", err)
 	}
@@ -48,6 +57,20 @@ func main() {
 	fmt.Println(sud)
 }
 
+// Pasted in problems regularly have typos that leave them with no solution or
+// with several, say so up front rather than printing a surprising answer.
+func warnIfNotUnique(sud *sodacouplib.SudokuSquare) {
+	n, err := sodacouplib.CountSolutions(sud, 2)
+	if err != nil {
+		printFatal("error: %s", err)
+	}
+	if n == 0 {
+		fmt.Fprintln(os.Stderr, "WARNING: problem has no solution")
+	} else if n > 1 {
+		fmt.Fprintln(os.Stderr, "WARNING: problem has more than one solution, showing just one of them")
+	}
+}
+
 func parseCommandline() (bool, bool) {
 	verbose := len(os.Args) > 1 && os.Args[1] == "-v"
 	doSample := (!verbose && len(os.Args) > 1) || (verbose && len(os.Args) > 2)
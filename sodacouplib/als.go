@@ -0,0 +1,433 @@
+package sodacouplib
+
+import (
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// An almost locked set is n cells in one house with n+1 candidates between
+// them. Take away any one of the candidates and the rest are locked in
+// those cells, which is what the ALS techniques build on.
+type almostLockedSet struct {
+	house string
+	cells cellSet
+	mask  uint32 // the n+1 candidates
+	// places are the cells of the set that could be each value, and seenBy
+	// the cells outside it that see every one of those places.
+	places, seenBy []cellSet
+}
+
+// The biggest almost locked sets looked for. Bigger ones are rarely any use
+// and there can be a lot of them.
+const maxALSSize = 6
+
+// Every almost locked set in the square, each only once even if its cells
+// share more than one house.
+func almostLockedSets(sud *SudokuSquare) []almostLockedSet {
+	n := sud.size()
+	if sud.nines == nil {
+		sud.createNonagons()
+	}
+	var sets []almostLockedSet
+	seen := make(map[[maxALSSize]int]bool)
+	for _, nona := range sud.nines {
+		var unset []int
+		for _, cell := range nona.cells {
+			if !cell.isSet {
+				unset = append(unset, cell.row*n+cell.col)
+			}
+		}
+		sort.Ints(unset) // so the same cells make the same key in any house
+		var cells []int
+		var recurse func(from int, mask uint32)
+		recurse = func(from int, mask uint32) {
+			if len(cells) > 0 && bits.OnesCount32(mask) == len(cells)+1 {
+				var key [maxALSSize]int
+				for i := range key {
+					key[i] = -1
+				}
+				copy(key[:], cells)
+				if !seen[key] {
+					seen[key] = true
+					sets = append(sets, newAlmostLockedSet(sud, nona.name, cells, mask))
+				}
+			}
+			if len(cells) == maxALSSize {
+				return
+			}
+			for i := from; i < len(unset); i++ {
+				cells = append(cells, unset[i])
+				recurse(i+1, mask|sud.cellAt(unset[i]).candidates)
+				cells = cells[:len(cells)-1]
+			}
+		}
+		recurse(0, 0)
+	}
+	return sets
+}
+
+func newAlmostLockedSet(sud *SudokuSquare, house string, cells []int, mask uint32) almostLockedSet {
+	n := sud.size()
+	// its cellSets all come out of the one allocation
+	words := len(newCellSet(n * n))
+	free := make(cellSet, words*(1+2*bits.OnesCount32(mask)))
+	next := func() cellSet {
+		s := free[:words:words]
+		free = free[words:]
+		return s
+	}
+	a := almostLockedSet{
+		house:  house,
+		cells:  next(),
+		mask:   mask,
+		places: make([]cellSet, n+1),
+		seenBy: make([]cellSet, n+1),
+	}
+	for _, idx := range cells {
+		a.cells.add(idx)
+	}
+	for val := 1; val <= n; val++ {
+		if mask&(1<<val) == 0 {
+			continue
+		}
+		a.places[val], a.seenBy[val] = next(), next()
+		for i := range a.seenBy[val] {
+			a.seenBy[val][i] = ^uint64(0)
+		}
+		for _, idx := range cells {
+			if sud.cellAt(idx).hasCandidate(val) {
+				a.places[val].add(idx)
+				for i, w := range sud.geo.peerSets[idx] {
+					a.seenBy[val][i] &= w
+				}
+			}
+		}
+	}
+	return a
+}
+
+// An alsLink is from one almost locked set to another with restricted
+// common candidates. A restricted common is a value every place for which
+// in the one set sees every place for it in the other, so at most one set
+// can have it. Then the other is locked without it.
+type alsLink struct {
+	other int    // its place in the list of sets
+	rccs  uint32 // the restricted commons
+}
+
+// The links of every set to the others, indexed by their places in sets.
+// Rather than trying every pair, it looks for the sets whose first place
+// for a value is among the cells seeing every place for it in the set.
+func restrictedCommonsOf(sets []almostLockedSet, with []cellSet) [][]alsLink {
+	n := len(with) - 1
+	// the sets by value and the first cell that could be it
+	first := make([][][]int, n+1)
+	for val := 1; val <= n; val++ {
+		first[val] = make([][]int, n*n)
+	}
+	for j, b := range sets {
+		for val := 1; val <= n; val++ {
+			if b.mask&(1<<val) != 0 {
+				idx := b.places[val].first()
+				first[val][idx] = append(first[val][idx], j)
+			}
+		}
+	}
+	links := make([][]alsLink, len(sets))
+	rccs := make([]uint32, len(sets)) // with each other set, for the one at i
+	for i, a := range sets {
+		var others []int
+		for val := 1; val <= n; val++ {
+			if a.mask&(1<<val) == 0 {
+				continue
+			}
+			a.seenBy[val].and(with[val]).each(func(idx int) {
+				for _, j := range first[val][idx] {
+					if !sets[j].places[val].within(a.seenBy[val]) {
+						continue
+					}
+					if rccs[j] == 0 {
+						if sets[j].cells.intersects(a.cells) {
+							continue
+						}
+						others = append(others, j)
+					}
+					rccs[j] |= 1 << uint(val)
+				}
+			})
+		}
+		links[i] = make([]alsLink, len(others))
+		for l, j := range others {
+			links[i][l] = alsLink{j, rccs[j]}
+			rccs[j] = 0
+		}
+	}
+	return links
+}
+
+// The cells still with each value, indexed by value.
+func candidateSets(sud *SudokuSquare) []cellSet {
+	n := sud.size()
+	with := make([]cellSet, n+1)
+	for val := 1; val <= n; val++ {
+		with[val] = newCellSet(n * n)
+	}
+	for idx := 0; idx < n*n; idx++ {
+		for _, val := range cellDigits(sud.cellAt(idx)) {
+			with[val].add(idx)
+		}
+	}
+	return with
+}
+
+// The cells outside the sets that could be val and see every place for it
+// in all of them.
+func seeingAll(with []cellSet, val int, sets ...almostLockedSet) cellSet {
+	seeing := with[val]
+	for _, a := range sets {
+		seeing = seeing.and(a.seenBy[val]).andNot(a.cells)
+	}
+	return seeing
+}
+
+// Whether seeingAll would find anything outside skip, without making the
+// set, as it's asked a lot more often than it finds anything.
+func hasSeeingAll(with []cellSet, val int, skip cellSet, sets ...almostLockedSet) bool {
+	for i, w := range with[val] {
+		w &^= skip[i]
+		for _, a := range sets {
+			w &= a.seenBy[val][i] &^ a.cells[i]
+		}
+		if w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Makes the step of the sets taking out the eliminations, indexed by value,
+// or nil if there aren't any.
+func alsStep(sud *SudokuSquare, technique string, elims []cellSet, sets ...almostLockedSet) *Step {
+	found := false
+	for _, cells := range elims {
+		found = found || !cells.isEmpty()
+	}
+	if !found {
+		return nil
+	}
+	st := Step{Technique: technique}
+	var houses []string
+	for _, a := range sets {
+		houses = append(houses, a.house)
+		for _, idx := range a.cells.cells() {
+			st.addCell(sud.cellAt(idx))
+		}
+	}
+	st.House = strings.Join(houses, " & ")
+	for val, cells := range elims {
+		for _, idx := range cells.cells() {
+			st.eliminate(sud.cellAt(idx), val)
+		}
+	}
+	return &st
+}
+
+// Runs find until it stops finding anything, reporting each step. find
+// works from a fresh list of almost locked sets, and of the cells with each
+// value, each time, as each step makes the old ones out of date.
+func untilNoALSStep(sud *SudokuSquare, step StepFunc, find func(sets []almostLockedSet, with []cellSet) *Step) (bool, error) {
+	changes := false
+	for {
+		st := find(almostLockedSets(sud), candidateSets(sud))
+		if st == nil {
+			return changes, nil
+		}
+		changes = true
+		if e := report(step, *st); e != nil {
+			return true, e
+		}
+	}
+}
+
+// ALS-XZ: two almost locked sets A and B with a restricted common candidate
+// x. One of them is locked without x, so any other value z they share has
+// to be in one or the other, and can go from the cells seeing every z in
+// both. With two restricted commons both sets are locked, so each of their
+// values can go from the cells seeing every place for it in its set.
+func alsXZ(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
+	return untilNoALSStep(sud, step, func(sets []almostLockedSet, with []cellSet) *Step {
+		links := restrictedCommonsOf(sets, with)
+		for i, a := range sets {
+			for _, link := range links[i] {
+				if link.other < i {
+					continue
+				}
+				b, x := sets[link.other], link.rccs
+				var elims []cellSet
+				for val := 1; val <= n; val++ {
+					// the one restricted common, if there's only one, stays
+					if (a.mask&b.mask)&(1<<val) != 0 && x != 1<<val && hasSeeingAll(with, val, a.cells, a, b) {
+						if elims == nil {
+							elims = make([]cellSet, n+1)
+						}
+						elims[val] = seeingAll(with, val, a, b)
+					}
+				}
+				if bits.OnesCount32(x) > 1 {
+					if elims == nil {
+						elims = make([]cellSet, n+1)
+					}
+					for val := 1; val <= n; val++ {
+						if x&(1<<val) != 0 {
+							continue
+						}
+						for _, s := range []almostLockedSet{a, b} {
+							if s.mask&(1<<val) != 0 {
+								more := seeingAll(with, val, s).andNot(a.cells).andNot(b.cells)
+								if elims[val] == nil {
+									elims[val] = more
+								} else {
+									elims[val] = elims[val].or(more)
+								}
+							}
+						}
+					}
+				}
+				if st := alsStep(sud, "ALS-XZ", elims, a, b); st != nil {
+					return st
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ALS-XY-Wing: a pivot set C with a restricted common candidate x with set
+// A, and another y with set B. If A doesn't have x, C does, so C doesn't
+// have y and B does... either way A or B is locked, and a value z they share
+// can go from the cells that see every z in both.
+func alsXYWing(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
+	return untilNoALSStep(sud, step, func(sets []almostLockedSet, with []cellSet) *Step {
+		links := restrictedCommonsOf(sets, with)
+		// the values each set has that some other cell sees every place for,
+		// as z can only go if it's one of those for both A and B
+		reach := make([]uint32, len(sets))
+		for i, a := range sets {
+			for val := 1; val <= n; val++ {
+				if a.mask&(1<<val) != 0 && hasSeeingAll(with, val, a.cells, a) {
+					reach[i] |= 1 << uint(val)
+				}
+			}
+		}
+		for k, c := range sets {
+			for ii, la := range links[k] {
+				if reach[la.other] == 0 {
+					continue
+				}
+				for _, lb := range links[k][ii+1:] {
+					zs := reach[la.other] & reach[lb.other]
+					if zs == 0 || !differentLinks(la.rccs, lb.rccs) || sets[la.other].cells.intersects(sets[lb.other].cells) {
+						continue
+					}
+					a, b := sets[la.other], sets[lb.other]
+					var elims []cellSet
+					for ; zs != 0; zs &= zs - 1 {
+						val := bits.TrailingZeros32(zs)
+						// z can't be the x or y it's linked through
+						if !differentLinks(la.rccs&^(1<<uint(val)), lb.rccs&^(1<<uint(val))) {
+							continue
+						}
+						if hasSeeingAll(with, val, c.cells, a, b) {
+							if elims == nil {
+								elims = make([]cellSet, n+1)
+							}
+							elims[val] = seeingAll(with, val, a, b).andNot(c.cells)
+						}
+					}
+					if elims != nil {
+						return alsStep(sud, "ALS-XY-Wing", elims, a, b, c)
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Whether one restricted common can be picked from each of the masks
+// without them being the same.
+func differentLinks(x, y uint32) bool {
+	return x != 0 && y != 0 && !(x == y && bits.OnesCount32(x) == 1)
+}
+
+// Death Blossom: a stem cell and, for each of its candidates, an almost
+// locked set (a petal) whose every place for that candidate sees the stem.
+// Whatever the stem is, the petal for it is locked without it. So a value
+// z every petal has, and the stem doesn't, is in one of the petals and can
+// go from the cells seeing every z in all of them.
+func deathBlossom(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
+	return untilNoALSStep(sud, step, func(sets []almostLockedSet, with []cellSet) *Step {
+		for stem := 0; stem < n*n; stem++ {
+			digits := cellDigits(sud.cellAt(stem))
+			if len(digits) < 2 || len(digits) > 3 {
+				continue
+			}
+			// the sets that could be the petal for each of the stem's values
+			petals := make([][]almostLockedSet, len(digits))
+			for i, val := range digits {
+				for _, a := range sets {
+					if a.mask&(1<<val) != 0 && !a.cells.has(stem) && a.seenBy[val].has(stem) {
+						petals[i] = append(petals[i], a)
+					}
+				}
+			}
+			for z := 1; z <= n; z++ {
+				if sud.cellAt(stem).hasCandidate(z) {
+					continue
+				}
+				if st := blossom(sud, stem, z, petals, nil, with[z]); st != nil {
+					return st
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Picks a petal for each of the stem's values in turn, all with z and not
+// overlapping, narrowing down the cells that see every z in them as it
+// goes, and makes the step if there are any left at the end.
+func blossom(sud *SudokuSquare, stem, z int, petals [][]almostLockedSet, picked []almostLockedSet, seeing cellSet) *Step {
+	if seeing.isEmpty() {
+		return nil
+	}
+	if len(picked) == len(petals) {
+		elims := make([]cellSet, sud.size()+1)
+		elims[z] = seeing
+		st := alsStep(sud, "Death Blossom", elims, picked...)
+		if st != nil {
+			st.Cells = append([]CellRef{{stem / sud.size(), stem % sud.size()}}, st.Cells...)
+		}
+		return st
+	}
+	for _, p := range petals[len(picked)] {
+		if p.mask&(1<<z) == 0 {
+			continue
+		}
+		clash := false
+		for _, q := range picked {
+			clash = clash || p.cells.intersects(q.cells)
+		}
+		if clash {
+			continue
+		}
+		if st := blossom(sud, stem, z, petals, append(picked, p), seeing.and(p.seenBy[z]).andNot(p.cells)); st != nil {
+			return st
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAlmostLockedSets(t *testing.T) {
+	s := newEmptySudoku()
+	s.cells[0][0].candidates = candidatesOf(1, 2)
+	s.cells[0][4].candidates = candidatesOf(1, 3)
+
+	var found [][]int
+	for _, a := range almostLockedSets(s) {
+		found = append(found, a.cells.cells())
+	}
+	// (0,0) is in row 0, column 0 and block 0 but only comes up once
+	assert.Equal(t, [][]int{{0}, {0, 4}, {4}}, found)
+}
+
+func TestALSXZ(t *testing.T) {
+	t.Run("one restricted common", func(t *testing.T) {
+		s := newEmptySudoku()
+		s.cells[0][0].candidates = candidatesOf(1, 2) // A
+		s.cells[0][4].candidates = candidatesOf(1, 3) // B, down column 4
+		s.cells[4][4].candidates = candidatesOf(2, 3)
+
+		// 1 is the restricted common, so 2 is in A or B
+		assert.Equal(t, []Step{{
+			Technique:    "ALS-XZ",
+			House:        "row 0 & column 4",
+			Cells:        []CellRef{{0, 0}, {0, 4}, {4, 4}},
+			Eliminations: []Candidate{{4, 0, 2}},
+		}}, collectSteps(t, alsXZ, s))
+		noOpCheck(t, alsXZ, s)
+	})
+	t.Run("two restricted commons lock both sets", func(t *testing.T) {
+		s := newEmptySudoku()
+		s.cells[0][0].candidates = candidatesOf(1, 3) // A, along row 0
+		s.cells[0][1].candidates = candidatesOf(2, 3)
+		s.cells[1][2].candidates = candidatesOf(1, 2) // B
+
+		steps := collectSteps(t, alsXZ, s)
+		assert.Equal(t, 1, len(steps))
+		// 3 is locked in A, so goes from the rest of row 0
+		for col := 2; col < 9; col++ {
+			assert.Contains(t, steps[0].Eliminations, Candidate{0, col, 3})
+		}
+		assert.Equal(t, true, s.cells[0][0].hasCandidate(3))
+		assert.Equal(t, true, s.cells[1][2].hasCandidate(1))
+		noOpCheck(t, alsXZ, s)
+	})
+}
+
+func TestALSXYWing(t *testing.T) {
+	s := newEmptySudoku()
+	s.cells[0][0].candidates = candidatesOf(1, 2) // A
+	s.cells[4][4].candidates = candidatesOf(2, 3) // B
+	s.cells[0][4].candidates = candidatesOf(1, 3) // C, the pivot
+
+	assert.Equal(t, []Step{{
+		Technique:    "ALS-XY-Wing",
+		House:        "row 0 & row 4 & row 0",
+		Cells:        []CellRef{{0, 0}, {4, 4}, {0, 4}},
+		Eliminations: []Candidate{{4, 0, 2}},
+	}}, collectSteps(t, alsXYWing, s))
+	noOpCheck(t, alsXYWing, s)
+}
+
+func TestDeathBlossom(t *testing.T) {
+	s := newEmptySudoku()
+	s.cells[4][4].candidates = candidatesOf(1, 2) // the stem
+	s.cells[4][0].candidates = candidatesOf(1, 5) // the petal for 1
+	s.cells[0][4].candidates = candidatesOf(2, 5) // the petal for 2
+
+	assert.Equal(t, []Step{{
+		Technique:    "Death Blossom",
+		House:        "row 4 & row 0",
+		Cells:        []CellRef{{4, 4}, {4, 0}, {0, 4}},
+		Eliminations: []Candidate{{0, 0, 5}},
+	}}, collectSteps(t, deathBlossom, s))
+	noOpCheck(t, deathBlossom, s)
+}
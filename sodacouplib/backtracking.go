@@ -1,90 +1,296 @@
 package sodacouplib
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
 
 // backTrack Finds a solution SudokuSquare using a backtrackling algorithm.
-// (Doesn't check the resulting solution is unique).
-func backTrack(sud *SudokuSquare) (bool, error) {
-	var cells [9][9]byte
-	copyFrom(*sud, &cells)
-	if backTrackRecursive(&cells, 0, 0) {
+// (Doesn't check the resulting solution is unique, see CountSolutions for that).
+// Each value tried in a cell is a node of b.
+func backTrack(sud *SudokuSquare, b *budget) (bool, error) {
+	cells := copyFrom(sud)
+	found, err := backTrackRecursive(cells, 0, 0, b)
+	if err != nil {
+		return false, err
+	}
+	if found {
 		copyTo(cells, sud)
 		return false, nil
 	}
-	return false, errors.New("failed to converge")
+	return false, errNoSolution()
+}
+
+// backTrackStep searches the square out to a solution with solve (backTrack,
+// say), within b, and records the cells it filled in as a single Step.
+func backTrackStep(sud *SudokuSquare, solve func(*SudokuSquare, *budget) (bool, error), b *budget) (Step, error) {
+	st := Step{Technique: "Backtracking"}
+	var unset []CellRef
+	for r := range sud.cells {
+		for c := range sud.cells[r] {
+			if !sud.cells[r][c].isSet {
+				unset = append(unset, CellRef{r, c})
+			}
+		}
+	}
+	if _, e := solve(sud, b); e != nil {
+		return st, e
+	}
+	for _, ref := range unset {
+		st.Cells = append(st.Cells, ref)
+		val := int(sud.cells[ref.Row][ref.Col].value)
+		st.Placements = append(st.Placements, Candidate{ref.Row, ref.Col, val})
+	}
+	return st, nil
 }
 
-func backTrackRecursive(cells *[9][9]byte, row, col int) bool {
-	if col == 9 {
+func backTrackRecursive(cells grid, row, col int, b *budget) (bool, error) {
+	size := cells.geo.size
+	if col == size {
 		col = 0
 		row++
 	}
-	if row == 9 {
-		return true
+	if row == size {
+		return true, nil
 	}
-	cell := cells[row][col]
+	cell := cells.at(row, col)
 	if isSet(cell) {
-		return backTrackRecursive(cells, row, col+1)
+		return backTrackRecursive(cells, row, col+1, b)
 	}
-	for n := 1; n <= 9; n++ {
+	for n := 1; n <= size; n++ {
 		if isValidMove(cells, row, col, n) {
-			cells[row][col] = byte(n)
-			success := backTrackRecursive(cells, row, col+1)
-			if success {
-				return true
+			if err := b.spend(); err != nil {
+				return false, err
 			}
-			cells[row][col] = 0
+			cells.set(row, col, byte(n))
+			success, err := backTrackRecursive(cells, row, col+1, b)
+			if success || err != nil {
+				return success, err
+			}
+			cells.set(row, col, 0)
 		}
 	}
-	return false
+	return false, nil
 }
 
-func isValidMove(cells *[9][9]byte, row, col, val int) bool {
-	n := byte(val)
-	if isSet(cells[row][col]) {
-		return false
+// CountSolutions counts the solutions of the problem by backtracking through
+// all of them, giving up once `limit` have been found.
+func CountSolutions(sud *SudokuSquare, limit int) (int, error) {
+	if limit < 1 {
+		return 0, fmt.Errorf("limit must be at least 1, got %d", limit)
 	}
-	for r := 0; r < 9; r++ {
-		if cells[r][col] == n {
-			return false
+	return countSolutions(sud, limit), nil
+}
+
+func countSolutions(sud *SudokuSquare, limit int) int {
+	count, _ := countSolutionsWithin(sud, limit, nil) // can't run out
+	return count
+}
+
+// countSolutions within a budget, each branch taken being a node. Errors only
+// if the budget runs out.
+func countSolutionsWithin(sud *SudokuSquare, limit int, b *budget) (int, error) {
+	// The heuristics only make deductions that every solution agrees with,
+	// so running them first leaves the count alone but leaves a lot less to
+	// backtrack through. Them hitting a contradiction means no solutions.
+	sud = sud.clone()
+	if _, err := trySolveWithHeuristics(sud, countingStrategies(), ignoreSteps, b); err != nil {
+		var spent *BudgetError
+		if errors.As(err, &spent) {
+			return 0, err
 		}
+		return 0, nil
 	}
-	for c := 0; c < 9; c++ {
-		if cells[row][c] == n {
-			return false
+	count := 0
+	err := countRecursive(sud, &count, limit, b)
+	return count, err
+}
+
+// The heuristics countSolutions runs first: only the cheap ones, as on a
+// board with lots of solutions (a part filled one, say) the likes of chains
+// and almost locked sets cost more than the backtracking they save.
+func countingStrategies() *StrategySet {
+	set, err := NewStrategySet("Naked Single", "Hidden Single", "Cage Sum", "Pointing Pair", "Claiming Pair", "Naked Pair", "Hidden Pair")
+	if err != nil {
+		panic(err) // all registered
+	}
+	return set
+}
+
+// IsUnique reports whether the problem has exactly one solution.
+func IsUnique(sud *SudokuSquare) (bool, error) {
+	n, err := CountSolutions(sud, 2)
+	return n == 1, err
+}
+
+// Counts solutions by always branching on the unset cell with the fewest
+// candidates left, which for counting (unlike backTrack, where the order
+// decides which solution comes out) is all that matters and is a lot quicker
+// on bigger boards.
+func countRecursive(sud *SudokuSquare, count *int, limit int, b *budget) error {
+	if propagateSingles(sud) != nil {
+		return nil // dead end
+	}
+	var best *SudokuCell
+	fewest := maxSize + 1
+	for r := range sud.cells {
+		for c := range sud.cells[r] {
+			cell := &sud.cells[r][c]
+			if cell.isSet {
+				continue
+			}
+			n := bits.OnesCount32(cell.candidates)
+			if n == 0 {
+				return nil // dead end
+			}
+			if n < fewest {
+				best, fewest = cell, n
+			}
 		}
 	}
-	blkRow := row - row%3
-	blkCol := col - col%3
-	for r := blkRow; r < blkRow+3; r++ {
-		for c := blkCol; c < blkCol+3; c++ {
-			if cells[r][c] == n {
+	if best == nil {
+		*count++
+		return nil
+	}
+	for val := 1; val <= sud.size() && *count < limit; val++ {
+		if best.hasCandidate(val) {
+			if err := b.spend(); err != nil {
+				return err
+			}
+			next := sud.clone()
+			if e := next.setCell(best.row, best.col, val); e != nil {
+				panic(e) // was a candidate so can't fail
+			}
+			if err := countRecursive(next, count, limit, b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Fills in naked and hidden singles until there are none left, which keeps
+// the search tree small. Errors if that runs into a contradiction. Works on
+// the houses directly rather than through nakedSingle/hiddenSingle and
+// sanityCheck, as it runs at every node of the search and those allocate.
+func propagateSingles(sud *SudokuSquare) error {
+	n := sud.size()
+	all := allCandidates(n)
+	for changed := true; changed; {
+		changed = false
+		for idx := 0; idx < n*n; idx++ {
+			cell := sud.cellAt(idx)
+			if cell.isSet {
+				continue
+			}
+			switch bits.OnesCount32(cell.candidates) {
+			case 0:
+				return cellError(ErrContradiction, cell.row, cell.col, 0, "", "cell %d,%d has no candidates left", cell.row, cell.col)
+			case 1:
+				val := bits.TrailingZeros32(cell.candidates)
+				if e := sud.setCell(cell.row, cell.col, val); e != nil {
+					return e
+				}
+				changed = true
+			}
+		}
+		for _, h := range sud.geo.houses {
+			var set, once, twice uint32
+			for _, idx := range h.cells {
+				cell := sud.cellAt(idx)
+				if cell.isSet {
+					set |= 1 << cell.value
+				} else {
+					twice |= once & cell.candidates
+					once |= cell.candidates
+				}
+			}
+			if missing := all &^ (set | once); missing != 0 {
+				val := bits.TrailingZeros32(missing)
+				return houseError(ErrContradiction, h.name, val, "%s has nowhere left for %d", h.name, val)
+			}
+			hidden := once &^ twice &^ set
+			for _, idx := range h.cells {
+				cell := sud.cellAt(idx)
+				if !cell.isSet && cell.candidates&hidden != 0 {
+					val := bits.TrailingZeros32(cell.candidates & hidden)
+					if e := sud.setCell(cell.row, cell.col, val); e != nil {
+						return e
+					}
+					changed = true
+					break // the rest get picked up on the next time round
+				}
+			}
+		}
+		// singles alone would happily fill a cage with the wrong sum
+		caged, err := cageSum(sud, ignoreSteps)
+		if err != nil {
+			return err
+		}
+		changed = changed || caged
+	}
+	return nil
+}
+
+// Checks val isn't already in any of the houses (row, column, block) the
+// cell is in, and that its cage, if it has one, still adds up.
+func isValidMove(cells grid, row, col, val int) bool {
+	n := byte(val)
+	idx := row*cells.geo.size + col
+	if isSet(cells.cells[idx]) {
+		return false
+	}
+	for _, h := range cells.geo.cellHouses[idx] {
+		for _, i := range cells.geo.houses[h].cells {
+			if cells.cells[i] == n {
 				return false
 			}
 		}
 	}
-	return true
+	return cageAllows(cells, idx, val)
 }
 
 func isSet(cell byte) bool {
 	return cell != 0
 }
 
-func copyFrom(sud SudokuSquare, cells *[9][9]byte) {
-	for r := 0; r < 9; r++ {
-		for c := 0; c < 9; c++ {
+// A grid is just the values of a square's cells, row-major with 0 for unset,
+// which is all backtracking needs.
+type grid struct {
+	geo   *geometry
+	cells []byte
+}
+
+func (g grid) at(row, col int) byte {
+	return g.cells[row*g.geo.size+col]
+}
+
+func (g grid) set(row, col int, val byte) {
+	g.cells[row*g.geo.size+col] = val
+}
+
+func (g grid) clone() grid {
+	return grid{g.geo, append([]byte(nil), g.cells...)}
+}
+
+func copyFrom(sud *SudokuSquare) grid {
+	cells := grid{sud.geo, make([]byte, sud.size()*sud.size())}
+	for r := range sud.cells {
+		for c := range sud.cells[r] {
 			if sud.cells[r][c].isSet {
-				cells[r][c] = sud.cells[r][c].value
+				cells.set(r, c, sud.cells[r][c].value)
 			}
 		}
 	}
+	return cells
 }
 
-func copyTo(cells [9][9]byte, sud *SudokuSquare) {
-	for r := 0; r < 9; r++ {
-		for c := 0; c < 9; c++ {
-			if isSet(cells[r][c]) && !sud.cells[r][c].isSet {
-				e := sud.setCell(r, c, int(cells[r][c]))
+func copyTo(cells grid, sud *SudokuSquare) {
+	for r := range sud.cells {
+		for c := range sud.cells[r] {
+			if isSet(cells.at(r, c)) && !sud.cells[r][c].isSet {
+				e := sud.setCell(r, c, int(cells.at(r, c)))
 				if e != nil {
 					panic(e) // something completely wrong if we end up here
 				}
@@ -92,3 +298,10 @@ func copyTo(cells [9][9]byte, sud *SudokuSquare) {
 		}
 	}
 }
+
+// A new square with just the values in the grid filled in.
+func (g grid) toSquare() *SudokuSquare {
+	sud := newEmptySudokuWithGeometry(g.geo)
+	copyTo(g, sud)
+	return sud
+}
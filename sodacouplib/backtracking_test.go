@@ -23,7 +23,7 @@ func TestBacktracking_unsolvableProblem(t *testing.T) {
 	if err != nil {
 		t.Fatal("got unexpected error from valid input:", err)
 	}
-	_, err = backTrack(s)
+	_, err = backTrack(s, nil)
 	if err == nil {
 		t.Fatal("got unexpected success (!) on bad problem")
 	}
@@ -95,35 +95,207 @@ func TestBacktracking_edgeCases(t *testing.T) {
 	})
 }
 
+// Puzzles with one solution each, and that solution.
+var backtrackingProblems = []struct{ name, problem, solution string }{{
+	"simple missing 8s",
+	`
+	123 456 7_9
+	456 7_9 123
+	7_9 123 456
+
+	234 567 _91
+	567 _91 234
+	_91 234 567
+
+	345 67_ 912
+	67_ 912 345
+	912 345 67_
+	`, `
+	123 456 789
+	456 789 123
+	789 123 456
+
+	234 567 891
+	567 891 234
+	891 234 567
+
+	345 678 912
+	678 912 345
+	912 345 678
+`}, {
+	"problem 1",
+	`
+	__5 __2 __4
+	___ 5__ ___
+	_9_ _7_ 8_1
+
+	___ 3__ ___
+	5__ 81_ 2_3
+	__6 ___ __7
+
+	_39 64_ ___
+	___ ___ ___
+	__7 __5 _2_
+
+	`, `
+
+	185 962 374
+	743 581 962
+	692 473 851
+
+	928 357 146
+	574 816 293
+	316 294 587
+
+	239 648 715
+	451 729 638
+	867 135 429
+`}, {
+	"problem 2",
+	`
+	__8 7_4 ___
+	45_ 82_ _36
+	2_3 6__ 9__
+
+	_12 _87 ___
+	_9_ 2_3 _5_
+	___ 14_ 89_
+
+	__7 __6 3_4
+	64_ _78 _21
+	___ 4_2 6__
+
+	`, `
+	968 734 215
+	451 829 736
+	273 651 948
+
+	512 987 463
+	894 263 157
+	736 145 892
+
+	127 596 384
+	649 378 521
+	385 412 679
+`}, {
+	"problem 3",
+	`
+	2__ 4__ 6__
+	_13 _28 __7
+	_76 _5_ 8__
+
+	9__ ___ _6_
+	__5 ___ 3__
+	_3_ ___ __9
+
+	__4 _3_ 92_
+	3__ 74_ 51_
+	__8 __5 __3
+	`, `
+	289 473 651
+	513 628 497
+	476 159 832
+
+	947 382 165
+	625 917 384
+	831 564 279
+
+	754 831 926
+	392 746 518
+	168 295 743
+`}, {
+	"problem 4",
+	`
+	___ ___ _92
+	___ 2_6 8_7
+	___ _71 5__
+
+	_64 3__ ___
+	95_ ___ _86
+	___ __8 45_
+
+	__6 48_ ___
+	4_8 6_5 ___
+	72_ ___ ___
+	`, `
+	687 543 192
+	541 296 837
+	239 871 564
+
+	864 352 719
+	953 714 286
+	172 968 453
+
+	316 487 925
+	498 625 371
+	725 139 648
+`}, {
+	"problem 5",
+	`
+	___ __9 ___
+	_9_ ___ _65
+	8__ 3__ ___
+
+	__3 ___ __6
+	___ 7__ 82_
+	__1 ___ 34_
+
+	__5 8__ ___
+	___ _37 ___
+	62_ 1__ __9
+	`, `
+	162 579 483
+	397 284 165
+	854 361 972
+
+	273 418 596
+	946 753 821
+	581 926 347
+
+	735 892 614
+	419 637 258
+	628 145 739
+	`},
+}
+
 func TestBacktracking_validProblems(t *testing.T) {
-	sampleProblems := []struct{ name, problem, solution string }{{
-		"simple missing 8s",
-		`
-		123 456 7_9
-		456 7_9 123
-		7_9 123 456
-
-		234 567 _91
-		567 _91 234
-		_91 234 567
-
-		345 67_ 912
-		67_ 912 345
-		912 345 67_
-		`, `
-		123 456 789
-		456 789 123
-		789 123 456
+	for _, tc := range backtrackingProblems {
+		tc := tc // for parallel
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			testRunBackTrack(t, tc.problem, tc.solution)
+		})
+	}
+}
 
-		234 567 891
-		567 891 234
-		891 234 567
+func BenchmarkBackTrack(b *testing.B) {
+	benchmarkSolver(b, backTrack)
+}
 
-		345 678 912
-		678 912 345
-		912 345 678
-	`}, {
-		"problem 1",
+// Times solve on each of backtrackingProblems, for comparing the ways of
+// searching out a solution.
+func benchmarkSolver(b *testing.B, solve func(*SudokuSquare, *budget) (bool, error)) {
+	for _, tc := range backtrackingProblems {
+		s, err := NewSudokuSquare(tc.problem)
+		if err != nil {
+			b.Fatal("got unexpected error from valid input:", err)
+		}
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := solve(s.clone(), nil); err != nil {
+					b.Fatal("got unexpected error from solving:", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCountSolutions(t *testing.T) {
+	sampleProblems := []struct {
+		name, problem string
+		limit, count  int
+	}{{
+		"unique",
 		`
 		__5 __2 __4
 		___ 5__ ___
@@ -136,134 +308,75 @@ func TestBacktracking_validProblems(t *testing.T) {
 		_39 64_ ___
 		___ ___ ___
 		__7 __5 _2_
-
-		`, `
-
+		`, 10, 1,
+	}, {
+		"two solutions",
+		`
 		185 962 374
 		743 581 962
-		692 473 851
+		692 473 __1
 
 		928 357 146
 		574 816 293
-		316 294 587
+		316 294 __7
 
 		239 648 715
 		451 729 638
 		867 135 429
-	`}, {
-		"problem 2",
-		`
-		__8 7_4 ___
-		45_ 82_ _36
-		2_3 6__ 9__
-
-		_12 _87 ___
-		_9_ 2_3 _5_
-		___ 14_ 89_
-
-		__7 __6 3_4
-		64_ _78 _21
-		___ 4_2 6__
-
-		`, `
-		968 734 215
-		451 829 736
-		273 651 948
-
-		512 987 463
-		894 263 157
-		736 145 892
-
-		127 596 384
-		649 378 521
-		385 412 679
-	`}, {
-		"problem 3",
-		`
-		2__ 4__ 6__
-		_13 _28 __7
-		_76 _5_ 8__
-
-		9__ ___ _6_
-		__5 ___ 3__
-		_3_ ___ __9
-
-		__4 _3_ 92_
-		3__ 74_ 51_
-		__8 __5 __3
-		`, `
-		289 473 651
-		513 628 497
-		476 159 832
-
-		947 382 165
-		625 917 384
-		831 564 279
-
-		754 831 926
-		392 746 518
-		168 295 743
-	`}, {
-		"problem 4",
+		`, 10, 2,
+	}, {
+		"no solutions",
 		`
-		___ ___ _92
-		___ 2_6 8_7
-		___ _71 5__
-
-		_64 3__ ___
-		95_ ___ _86
-		___ __8 45_
-
-		__6 48_ ___
-		4_8 6_5 ___
-		72_ ___ ___
-		`, `
-		687 543 192
-		541 296 837
-		239 871 564
-
-		864 352 719
-		953 714 286
-		172 968 453
-
-		316 487 925
-		498 625 371
-		725 139 648
-	`}, {
-		"problem 5",
+		1_3 456 729
+		426 789 1_3
+		789 123 456
+
+		214 365 897
+		365 897 214
+		897 214 365
+
+		531 642 978
+		642 978 531
+		978 531 642
+		`, 10, 0,
+	}, {
+		"stops at the limit",
 		`
-		___ __9 ___
-		_9_ ___ _65
-		8__ 3__ ___
-
-		__3 ___ __6
-		___ 7__ 82_
-		__1 ___ 34_
-
-		__5 8__ ___
-		___ _37 ___
-		62_ 1__ __9
-		`, `
-		162 579 483
-		397 284 165
-		854 361 972
-
-		273 418 596
-		946 753 821
-		581 926 347
-
-		735 892 614
-		419 637 258
-		628 145 739
-		`},
-	}
+		___ ___ ___
+		___ ___ ___
+		___ ___ ___
+
+		___ ___ ___
+		___ _1_ ___
+		___ ___ ___
+
+		___ ___ ___
+		___ ___ ___
+		___ ___ ___
+		`, 5, 5,
+	}}
 	for _, tc := range sampleProblems {
 		tc := tc // for parallel
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			testRunBackTrack(t, tc.problem, tc.solution)
+			s, err := NewSudokuSquare(tc.problem)
+			if err != nil {
+				t.Fatal("got unexpected error from valid input:", err)
+			}
+
+			count, err := CountSolutions(s, tc.limit)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.count, count)
+
+			unique, err := IsUnique(s)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.count == 1, unique)
 		})
 	}
+	t.Run("bad limit", func(t *testing.T) {
+		_, err := CountSolutions(newEmptySudoku(), 0)
+		assert.Error(t, err)
+	})
 }
 
 func testRunBackTrack(t *testing.T, problem, expected string) {
@@ -271,7 +384,7 @@ func testRunBackTrack(t *testing.T, problem, expected string) {
 		t.Error("got unexpected error from valid input:", err)
 	} else if s, err := NewSudokuSquare(problem); err != nil {
 		t.Error("got unexpected error from valid input:", err)
-	} else if _, err := backTrack(s); err != nil {
+	} else if _, err := backTrack(s, nil); err != nil {
 		t.Error("got unexpected error from solving:", err)
 	} else if result, err := FormatSudoku(s.String()); err != nil {
 		t.Error("got unexpected error from formatting result:", err)
@@ -0,0 +1,218 @@
+package sodacouplib
+
+import (
+	"math/bits"
+)
+
+// bitboard is a square cut down to what a fast search needs: the value in
+// each cell, and for each house (and cage) a mask of the values already in
+// it. What can go in a cell is then a few ORs of its houses' masks, rather
+// than the scan of every cell in them isValidMove does.
+type bitboard struct {
+	cells grid
+	all   uint32
+	// allowed are the candidates each cell started the search with, which
+	// can be fewer than its houses allow when the strategies have been at
+	// the square.
+	allowed []uint32
+	// used are the values in each house, and caged those in each cage.
+	used, caged []uint32
+	// trail is the cells filled in so far, in order, for undoing back to
+	// a guess.
+	trail []int
+	// budget has a node taken out of it for each guess.
+	budget *budget
+}
+
+// Errors if the square's givens already clash.
+func newBitboard(sud *SudokuSquare) (*bitboard, error) {
+	geo := sud.geo
+	n := geo.size
+	b := &bitboard{
+		cells:   grid{geo, make([]byte, n*n)},
+		all:     allCandidates(n),
+		allowed: make([]uint32, n*n),
+		used:    make([]uint32, len(geo.houses)),
+		caged:   make([]uint32, len(geo.cages)),
+	}
+	for idx := range b.allowed {
+		cell := sud.cellAt(idx)
+		if !cell.isSet {
+			b.allowed[idx] = cell.candidates
+			continue
+		}
+		val := int(cell.value)
+		b.allowed[idx] = b.all
+		if b.candidates(idx)&(1<<uint(val)) == 0 {
+			if err := duplicateClue(sud, idx, val); err != nil {
+				return nil, err
+			}
+			return nil, errNoSolution() // the givens break a cage's sum
+		}
+		b.place(idx, val)
+	}
+	b.trail = b.trail[:0] // givens aren't undone
+	return b, nil
+}
+
+// The values that can still go in an empty cell.
+func (b *bitboard) candidates(idx int) uint32 {
+	geo := b.cells.geo
+	mask := b.allowed[idx]
+	for _, h := range geo.cellHouses[idx] {
+		mask &^= b.used[h]
+	}
+	if c := geo.cellCage[idx]; c >= 0 {
+		mask &^= b.caged[c]
+		for m := mask; m != 0; m &= m - 1 {
+			val := bits.TrailingZeros32(m)
+			if !cageAllows(b.cells, idx, val) {
+				mask &^= 1 << uint(val)
+			}
+		}
+	}
+	return mask
+}
+
+func (b *bitboard) place(idx, val int) {
+	geo := b.cells.geo
+	bit := uint32(1) << uint(val)
+	b.cells.cells[idx] = byte(val)
+	for _, h := range geo.cellHouses[idx] {
+		b.used[h] |= bit
+	}
+	if c := geo.cellCage[idx]; c >= 0 {
+		b.caged[c] |= bit
+	}
+	b.trail = append(b.trail, idx)
+}
+
+// Empties the cells filled in since the trail was mark long.
+func (b *bitboard) undo(mark int) {
+	geo := b.cells.geo
+	for _, idx := range b.trail[mark:] {
+		bit := uint32(1) << b.cells.cells[idx]
+		b.cells.cells[idx] = 0
+		for _, h := range geo.cellHouses[idx] {
+			b.used[h] &^= bit
+		}
+		if c := geo.cellCage[idx]; c >= 0 {
+			b.caged[c] &^= bit
+		}
+	}
+	b.trail = b.trail[:mark]
+}
+
+// Fills in naked and hidden singles until there are none left, reporting
+// false if that runs into a cell with nothing that fits or a house with
+// nowhere left for a value.
+func (b *bitboard) propagate() bool {
+	geo := b.cells.geo
+	for changed := true; changed; {
+		changed = false
+		for idx, val := range b.cells.cells {
+			if isSet(val) {
+				continue
+			}
+			mask := b.candidates(idx)
+			switch bits.OnesCount32(mask) {
+			case 0:
+				return false
+			case 1:
+				b.place(idx, bits.TrailingZeros32(mask))
+				changed = true
+			}
+		}
+		for h, hs := range geo.houses {
+			var once, twice uint32
+			for _, idx := range hs.cells {
+				if !isSet(b.cells.cells[idx]) {
+					mask := b.candidates(idx)
+					twice |= once & mask
+					once |= mask
+				}
+			}
+			if (b.used[h]|once)&b.all != b.all {
+				return false
+			}
+			hidden := once &^ twice &^ b.used[h]
+			for _, idx := range hs.cells {
+				if hidden == 0 {
+					break
+				}
+				if isSet(b.cells.cells[idx]) {
+					continue
+				}
+				if mask := b.candidates(idx) & hidden; mask != 0 {
+					if bits.OnesCount32(mask) > 1 {
+						return false // two values with only this cell to go in
+					}
+					b.place(idx, bits.TrailingZeros32(mask))
+					hidden &^= mask
+					changed = true
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Searches for a solution, always guessing at the empty cell with the fewest
+// values left once the singles have been followed through. Leaves the
+// solution in the cells if there is one, otherwise undoes everything it
+// tried. Errors only if the budget runs out.
+func (b *bitboard) solve() (bool, error) {
+	mark := len(b.trail)
+	if !b.propagate() {
+		b.undo(mark)
+		return false, nil
+	}
+	best, fewest := -1, maxSize+1
+	var options uint32
+	for idx, val := range b.cells.cells {
+		if isSet(val) {
+			continue
+		}
+		mask := b.candidates(idx)
+		if count := bits.OnesCount32(mask); count < fewest {
+			best, fewest, options = idx, count, mask
+		}
+	}
+	if best < 0 {
+		return true, nil
+	}
+	for ; options != 0; options &= options - 1 {
+		if err := b.budget.spend(); err != nil {
+			return false, err
+		}
+		guess := len(b.trail)
+		b.place(best, bits.TrailingZeros32(options))
+		if solved, err := b.solve(); solved || err != nil {
+			return solved, err
+		}
+		b.undo(guess)
+	}
+	b.undo(mark)
+	return false, nil
+}
+
+// fastBackTrack finds a solution like backTrack, but on a bitboard, always
+// branching on the cell with fewest options and following through singles
+// after every guess. Which solution it finds for a puzzle with more than one
+// can differ from backTrack's. Each guess is a node of the budget.
+func fastBackTrack(sud *SudokuSquare, budget *budget) (bool, error) {
+	b, err := newBitboard(sud)
+	if err != nil {
+		return false, err
+	}
+	b.budget = budget
+	solved, err := b.solve()
+	if err != nil {
+		return false, err
+	}
+	if !solved {
+		return false, errNoSolution()
+	}
+	copyTo(b.cells, sud)
+	return false, nil
+}
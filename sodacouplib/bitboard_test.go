@@ -0,0 +1,73 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFastBackTrack(t *testing.T) {
+	for _, tc := range backtrackingProblems {
+		s, _ := NewSudokuSquare(tc.problem)
+		if _, err := fastBackTrack(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		assertSolution(t, s, tc.solution)
+	}
+	t.Run("few givens", func(t *testing.T) {
+		s, _ := NewSudokuSquare(seventeenClues)
+		expected := s.clone()
+		if _, err := dancingLinks(expected, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		if _, err := fastBackTrack(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		assert.Equal(t, expected.String(), s.String())
+	})
+	t.Run("all empty square", func(t *testing.T) {
+		s := newEmptySudoku()
+		if _, err := fastBackTrack(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		assert.Equal(t, true, isSolved(s))
+	})
+	t.Run("no solution", func(t *testing.T) {
+		s, _ := NewSudokuSquare(`
+			1_3 456 729
+			426 789 1_3
+			789 123 456
+
+			214 365 897
+			365 897 214
+			897 214 365
+
+			531 642 978
+			642 978 531
+			978 531 642
+		`)
+		_, err := fastBackTrack(s, nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to converge")
+	})
+	t.Run("cages", func(t *testing.T) {
+		cages, _ := ParseCages(killerCages)
+		s, _ := NewSudokuSquareWithVariant(killerProblem, ClassicLayout, Variant{Cages: cages})
+		if _, err := fastBackTrack(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		assertSolution(t, s, fullGrid)
+	})
+	t.Run("other sizes", func(t *testing.T) {
+		layout, _ := LayoutForSize(16)
+		s, _ := NewSudokuSquareWithLayout(patternGrid(layout, 3), layout)
+		if _, err := fastBackTrack(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		expected, _ := NewSudokuSquareWithLayout(patternGrid(layout, 0), layout)
+		assert.Equal(t, expected.String(), s.String())
+	})
+}
+
+func BenchmarkFastBackTrack(b *testing.B) {
+	benchmarkSolver(b, fastBackTrack)
+}
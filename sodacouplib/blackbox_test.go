@@ -184,7 +184,7 @@ func testSolvingSudoku(t *testing.T, problem, expected string) {
 		t.Error("got unexpected error from valid input:", err)
 	} else if s, err := NewSudokuSquare(problem); err != nil {
 		t.Error("got unexpected error from valid input:", err)
-	} else if err := s.Solve(); err != nil {
+	} else if _, err := s.Solve(SolveOptions{}); err != nil {
 		t.Error("got unexpected error from solving:", err)
 	} else if result, err := FormatSudoku(s.String()); err != nil {
 		t.Error("got unexpected error from formatting result:", err)
@@ -0,0 +1,63 @@
+package sodacouplib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNodeLimit is what a BudgetError wraps when it gave up for having
+// searched SolveOptions.MaxNodes nodes.
+var ErrNodeLimit = errors.New("node limit reached")
+
+// BudgetError is returned when solving or generating gives up part way
+// through, because its context was cancelled or passed its deadline, or
+// because it ran out of nodes. It unwraps to the context's error or to
+// ErrNodeLimit, so errors.Is tells which.
+type BudgetError struct {
+	// Nodes is how many nodes had been searched when it gave up.
+	Nodes int
+	Err   error
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("gave up after %d nodes: %s", e.Nodes, e.Err)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return e.Err
+}
+
+// A budget is how far a search may go before giving up: until ctx is done,
+// and for at most maxNodes nodes if that's above 0. A node is a strategy
+// being tried or a guess being made. It's only checked between nodes, so a
+// strategy that's already running gets to finish: one pass of the forcing
+// chains or almost locked sets on a hard puzzle can take a good while past
+// the deadline. A nil budget never runs out.
+type budget struct {
+	ctx      context.Context
+	maxNodes int
+	nodes    int
+}
+
+func newBudget(ctx context.Context, maxNodes int) *budget {
+	return &budget{ctx: ctx, maxNodes: maxNodes}
+}
+
+// spend takes a node out of the budget, erroring with a *BudgetError if
+// there wasn't one left.
+func (b *budget) spend() error {
+	if b == nil {
+		return nil
+	}
+	if b.maxNodes > 0 && b.nodes >= b.maxNodes {
+		return &BudgetError{b.nodes, ErrNodeLimit}
+	}
+	select {
+	case <-b.ctx.Done():
+		return &BudgetError{b.nodes, b.ctx.Err()}
+	default:
+	}
+	b.nodes++
+	return nil
+}
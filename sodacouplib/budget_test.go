@@ -0,0 +1,32 @@
+package sodacouplib
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBudget(t *testing.T) {
+	var unlimited *budget
+	assert.NoError(t, unlimited.spend())
+
+	b := newBudget(context.Background(), 3)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, b.spend())
+	}
+	err := b.spend()
+	assert.Equal(t, &BudgetError{3, ErrNodeLimit}, err)
+	assert.Equal(t, true, errors.Is(err, ErrNodeLimit))
+	assert.Equal(t, "gave up after 3 nodes: node limit reached", err.Error())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b = newBudget(ctx, 0)
+	assert.NoError(t, b.spend())
+	cancel()
+	err = b.spend()
+	assert.Equal(t, true, errors.Is(err, context.Canceled))
+	var spent *BudgetError
+	assert.Equal(t, true, errors.As(err, &spent))
+	assert.Equal(t, 1, spent.Nodes)
+}
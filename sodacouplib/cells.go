@@ -0,0 +1,152 @@
+package sodacouplib
+
+// Reading and changing the square a cell at a time, for something like a
+// game that lets a player fill it in and pencil marks out. Rows and columns
+// count from 0 and values from 1 up to Size, as everywhere else. Anything
+// outside that is a *CellError of kind ErrOutOfRange.
+
+// Size is the number of cells along each side of the square.
+func (sud *SudokuSquare) Size() int {
+	return sud.size()
+}
+
+// Get returns the value in the cell, 0 if it isn't set.
+func (sud *SudokuSquare) Get(row, col int) (int, error) {
+	if err := sud.checkCell(row, col); err != nil {
+		return 0, err
+	}
+	return int(sud.cells[row][col].value), nil
+}
+
+// Candidates returns the values still possible for the cell, smallest
+// first, or none if it's set.
+func (sud *SudokuSquare) Candidates(row, col int) ([]int, error) {
+	if err := sud.checkCell(row, col); err != nil {
+		return nil, err
+	}
+	return cellDigits(&sud.cells[row][col]), nil
+}
+
+// Set puts val in the cell, taking it out of the candidates of every cell
+// that sees it. A value already in the cell is cleared first. A val another
+// cell in one of its houses (or its cage) already has is ErrDuplicateClue,
+// and one that's been eliminated from the cell ErrContradiction; either way
+// the square is left as it was.
+func (sud *SudokuSquare) Set(row, col, val int) error {
+	if err := sud.checkValue(row, col, val); err != nil {
+		return err
+	}
+	c := &sud.cells[row][col]
+	old := int(c.value)
+	if old == val {
+		return nil
+	}
+	if err := duplicateClue(sud, row*sud.size()+col, val); err != nil {
+		return err
+	}
+	if c.isSet {
+		sud.clearCell(row, col)
+	}
+	if err := sud.setCell(row, col, val); err != nil {
+		if old != 0 {
+			// it was there before, so it can go back
+			sud.clearCell(row, col)
+			if e := sud.setCell(row, col, old); e != nil {
+				panic(e)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// Clear empties the cell. It gets back every value none of the cells it
+// sees has, and the value it had goes back into the candidates of the cells
+// that see it, unless another cell they see has it too. Anything else
+// eliminated from those cells stays eliminated. Clearing an empty cell does
+// nothing.
+func (sud *SudokuSquare) Clear(row, col int) error {
+	if err := sud.checkCell(row, col); err != nil {
+		return err
+	}
+	if sud.cells[row][col].isSet {
+		sud.clearCell(row, col)
+	}
+	return nil
+}
+
+// EliminateCandidate takes val out of the candidates of an unset cell. It
+// does nothing if val is already out, and is ErrContradiction if it's the
+// last candidate left or the cell is set to it.
+func (sud *SudokuSquare) EliminateCandidate(row, col, val int) error {
+	if err := sud.checkValue(row, col, val); err != nil {
+		return err
+	}
+	c := &sud.cells[row][col]
+	if c.isSet {
+		if int(c.value) == val {
+			return cellError(ErrContradiction, row, col, val, "", "%d,%d is set to %d", row, col, val)
+		}
+		return nil
+	}
+	if c.candidates == 1<<val {
+		return cellError(ErrContradiction, row, col, val, "", "%d is the last candidate left at %d,%d", val, row, col)
+	}
+	c.removeCandidate(val)
+	return nil
+}
+
+// Clone returns a copy of the square that can be changed without changing
+// the original.
+func (sud *SudokuSquare) Clone() *SudokuSquare {
+	return sud.clone()
+}
+
+// Unsets a set cell, working out its candidates again from the cells it
+// sees and giving its value back to them.
+func (sud *SudokuSquare) clearCell(row, col int) {
+	n := sud.size()
+	idx := row*n + col
+	c := &sud.cells[row][col]
+	val := int(c.value)
+	c.isSet = false
+	c.value = 0
+	c.candidates = sud.unseen(idx)
+	for _, p := range sud.geo.peers[idx] {
+		if peer := sud.cellAt(p); !peer.isSet && sud.unseen(p)&(1<<val) != 0 {
+			peer.candidates |= 1 << val
+		}
+	}
+}
+
+// Candidate mask of the values none of the cells the one at idx sees are
+// set to.
+func (sud *SudokuSquare) unseen(idx int) uint32 {
+	mask := allCandidates(sud.size())
+	for _, p := range sud.geo.peers[idx] {
+		if peer := sud.cellAt(p); peer.isSet {
+			mask &^= 1 << peer.value
+		}
+	}
+	return mask
+}
+
+// The error for a cell that isn't on the board, or nil.
+func (sud *SudokuSquare) checkCell(row, col int) error {
+	n := sud.size()
+	if row < 0 || row >= n || col < 0 || col >= n {
+		return cellError(ErrOutOfRange, row, col, 0, "", "%d,%d isn't on a %dx%d board", row, col, n, n)
+	}
+	return nil
+}
+
+// checkCell, and the error for a value that isn't on the board.
+func (sud *SudokuSquare) checkValue(row, col, val int) error {
+	if err := sud.checkCell(row, col); err != nil {
+		return err
+	}
+	if n := sud.size(); val < 1 || val > n {
+		return cellError(ErrOutOfRange, row, col, val, "", "%d isn't a value on a %dx%d board", val, n, n)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package sodacouplib
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetAndSet(t *testing.T) {
+	s := newEmptySudoku()
+	assert.Equal(t, 9, s.Size())
+	assert.NoError(t, s.Set(4, 4, 5))
+	val, err := s.Get(4, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, val)
+	val, _ = s.Get(4, 5)
+	assert.Equal(t, 0, val)
+
+	cands, _ := s.Candidates(4, 0)
+	assert.Equal(t, []int{1, 2, 3, 4, 6, 7, 8, 9}, cands)
+	cands, _ = s.Candidates(4, 4)
+	assert.Nil(t, cands)
+
+	t.Run("duplicate", func(t *testing.T) {
+		err := s.Set(4, 8, 5)
+		assert.Equal(t, true, errors.Is(err, ErrDuplicateClue))
+		val, _ := s.Get(4, 8)
+		assert.Equal(t, 0, val)
+	})
+	t.Run("eliminated", func(t *testing.T) {
+		assert.NoError(t, s.EliminateCandidate(0, 0, 7))
+		err := s.Set(0, 0, 7)
+		assert.Equal(t, true, errors.Is(err, ErrContradiction))
+	})
+	t.Run("overwrite", func(t *testing.T) {
+		assert.NoError(t, s.Set(4, 4, 6))
+		val, _ := s.Get(4, 4)
+		assert.Equal(t, 6, val)
+		assert.Equal(t, true, s.cells[4][0].hasCandidate(5))
+		assert.Equal(t, false, s.cells[4][0].hasCandidate(6))
+	})
+	t.Run("out of range", func(t *testing.T) {
+		for _, err := range []error{
+			s.Set(9, 0, 1),
+			s.Set(0, 0, 10),
+			s.Clear(0, -1),
+			s.EliminateCandidate(0, 0, 0),
+		} {
+			assert.Equal(t, true, errors.Is(err, ErrOutOfRange))
+		}
+		_, err := s.Get(0, 9)
+		assert.Equal(t, "0,9 isn't on a 9x9 board", err.Error())
+	})
+}
+
+func TestClear(t *testing.T) {
+	s := newEmptySudoku()
+	assert.NoError(t, s.Set(0, 0, 3))
+	assert.NoError(t, s.Set(0, 8, 2))
+	assert.NoError(t, s.Set(8, 1, 3))
+	assert.NoError(t, s.EliminateCandidate(0, 4, 9))
+
+	assert.NoError(t, s.Clear(0, 0))
+	val, _ := s.Get(0, 0)
+	assert.Equal(t, 0, val)
+	// 2 is still in the row, and the 3 in column 1 is still kept out of its
+	// block
+	assert.Equal(t, candidatesOf(1, 3, 4, 5, 6, 7, 8, 9), s.cells[0][0].candidates)
+	assert.Equal(t, true, s.cells[0][4].hasCandidate(3))
+	assert.Equal(t, false, s.cells[0][4].hasCandidate(9))
+	assert.Equal(t, false, s.cells[2][1].hasCandidate(3))
+
+	assert.NoError(t, s.Clear(0, 0))
+	_, err := sanityCheck(s)
+	assert.NoError(t, err)
+}
+
+func TestEliminateCandidate(t *testing.T) {
+	s := newEmptySudoku()
+	for val := 1; val < 9; val++ {
+		assert.NoError(t, s.EliminateCandidate(2, 2, val))
+	}
+	assert.NoError(t, s.EliminateCandidate(2, 2, 1))
+	err := s.EliminateCandidate(2, 2, 9)
+	var cellErr *CellError
+	assert.Equal(t, true, errors.As(err, &cellErr))
+	assert.Equal(t, ErrContradiction, cellErr.Kind)
+	cands, _ := s.Candidates(2, 2)
+	assert.Equal(t, []int{9}, cands)
+
+	assert.NoError(t, s.Set(2, 2, 9))
+	assert.Equal(t, true, errors.Is(s.EliminateCandidate(2, 2, 9), ErrContradiction))
+	assert.NoError(t, s.EliminateCandidate(2, 2, 4))
+}
+
+func TestClone(t *testing.T) {
+	s, _ := NewSudokuSquare(seventeenClues)
+	// a strategy has made the nonagons, which point into the cells
+	_, err := applyToNonagons(s, func(nonagon) (bool, error) { return false, nil })
+	assert.NoError(t, err)
+	before := s.asTableStringWithCandidates()
+
+	c := s.Clone()
+	assert.NoError(t, c.Set(0, 0, 5))
+	assert.NoError(t, c.EliminateCandidate(8, 8, 9))
+	_, err = applyToNonagons(c, func(h nonagon) (bool, error) {
+		for _, cell := range h.cells {
+			assert.Equal(t, &c.cells[cell.row][cell.col], cell)
+		}
+		return false, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, before, s.asTableStringWithCandidates())
+	val, _ := c.Get(0, 0)
+	assert.Equal(t, 5, val)
+}
@@ -0,0 +1,105 @@
+package sodacouplib
+
+import "math/bits"
+
+// A cellSet is a set of cells as a bitmap, bit row*size+col being whether
+// that cell is in it, for when cells have to be compared a group at a time.
+type cellSet []uint64
+
+// An empty set big enough for a board of that many cells.
+func newCellSet(cells int) cellSet {
+	return make(cellSet, (cells+63)/64)
+}
+
+func (s cellSet) add(idx int) {
+	s[idx/64] |= 1 << uint(idx%64)
+}
+
+func (s cellSet) has(idx int) bool {
+	return s[idx/64]&(1<<uint(idx%64)) != 0
+}
+
+func (s cellSet) isEmpty() bool {
+	for _, w := range s {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// A new set of the cells in both.
+func (s cellSet) and(t cellSet) cellSet {
+	both := make(cellSet, len(s))
+	for i := range s {
+		both[i] = s[i] & t[i]
+	}
+	return both
+}
+
+// A new set of the cells in s but not t.
+func (s cellSet) andNot(t cellSet) cellSet {
+	rest := make(cellSet, len(s))
+	for i := range s {
+		rest[i] = s[i] &^ t[i]
+	}
+	return rest
+}
+
+// A new set of the cells in either.
+func (s cellSet) or(t cellSet) cellSet {
+	either := make(cellSet, len(s))
+	for i := range s {
+		either[i] = s[i] | t[i]
+	}
+	return either
+}
+
+func (s cellSet) intersects(t cellSet) bool {
+	for i := range s {
+		if s[i]&t[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether every cell in s is in t.
+func (s cellSet) within(t cellSet) bool {
+	for i := range s {
+		if s[i]&^t[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// The cells in the set, in order.
+func (s cellSet) cells() []int {
+	var cells []int
+	s.each(func(idx int) {
+		cells = append(cells, idx)
+	})
+	return cells
+}
+
+// Calls fn with each cell in the set, in order.
+func (s cellSet) each(fn func(idx int)) {
+	for i, w := range s {
+		for w != 0 {
+			b := bits.TrailingZeros64(w)
+			fn(i*64 + b)
+			w &^= 1 << uint(b)
+		}
+	}
+}
+
+// The first cell in the set, or -1 if it's empty.
+func (s cellSet) first() int {
+	for i, w := range s {
+		if w != 0 {
+			return i*64 + bits.TrailingZeros64(w)
+		}
+	}
+	return -1
+}
@@ -0,0 +1,30 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCellSet(t *testing.T) {
+	a, b := newCellSet(81), newCellSet(81)
+	assert.Equal(t, 2, len(a))
+	assert.Equal(t, true, a.isEmpty())
+	assert.Equal(t, -1, a.first())
+
+	for _, idx := range []int{3, 64, 80} {
+		a.add(idx)
+	}
+	b.add(64)
+	assert.Equal(t, []int{3, 64, 80}, a.cells())
+	assert.Equal(t, 3, a.first())
+	assert.Equal(t, true, a.has(80))
+	assert.Equal(t, false, a.has(79))
+
+	assert.Equal(t, []int{64}, a.and(b).cells())
+	assert.Equal(t, []int{3, 80}, a.andNot(b).cells())
+	assert.Equal(t, []int{3, 64, 80}, b.or(a).cells())
+	assert.Equal(t, true, a.intersects(b))
+	assert.Equal(t, false, a.andNot(b).intersects(b))
+	assert.Equal(t, true, b.within(a))
+	assert.Equal(t, false, a.within(b))
+}
@@ -0,0 +1,343 @@
+package sodacouplib
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// The most links any chain is followed for, so the proofs stay short
+// enough for a person to check and the search stays quick.
+const maxChainLength = 12
+
+// chainGraph ties together every candidate left in the square, across
+// digits, for chains that can hop from one digit to another. Nodes are
+// candidates numbered cell*size + digit-1, cells being row*size+col.
+//
+// A strong link between two candidates means at least one of them is true:
+// the digit has only those two places in a house (bilocation), or the cell
+// has only those two digits left (bivalue). A weak link means at most one of
+// them is: the same digit in cells that see each other, or two digits in the
+// same cell. Every strong link is a weak link as well.
+type chainGraph struct {
+	sud          *SudokuSquare
+	strong, weak [][]int
+}
+
+func newChainGraph(sud *SudokuSquare) *chainGraph {
+	n := sud.size()
+	cg := &chainGraph{sud: sud, strong: make([][]int, n*n*n), weak: make([][]int, n*n*n)}
+	for digit := 1; digit <= n; digit++ {
+		g := sud.LinkGraph(digit)
+		for i, idx := range g.cells {
+			a := idx*n + digit - 1
+			for _, j := range g.strong[i] {
+				cg.strong[a] = append(cg.strong[a], g.cells[j]*n+digit-1)
+			}
+			for _, j := range g.linked[i] {
+				cg.weak[a] = append(cg.weak[a], g.cells[j]*n+digit-1)
+			}
+		}
+	}
+	for idx := 0; idx < n*n; idx++ {
+		digits := cellDigits(sud.cellAt(idx))
+		for _, x := range digits {
+			a := idx*n + x - 1
+			for _, y := range digits {
+				if y == x {
+					continue
+				}
+				cg.weak[a] = append(cg.weak[a], idx*n+y-1)
+				if len(digits) == 2 {
+					cg.strong[a] = append(cg.strong[a], idx*n+y-1)
+				}
+			}
+		}
+	}
+	return cg
+}
+
+// The candidates still left in a cell, in order.
+func cellDigits(cell *SudokuCell) []int {
+	var digits []int
+	if cell.isSet {
+		return digits
+	}
+	for val := 1; val <= maxSize; val++ {
+		if cell.hasCandidate(val) {
+			digits = append(digits, val)
+		}
+	}
+	return digits
+}
+
+// A chain state is a candidate taken to be true (on) or false (off),
+// numbered node*2+1 or node*2.
+func chainState(node int, on bool) int {
+	if on {
+		return node*2 + 1
+	}
+	return node * 2
+}
+
+func (cg *chainGraph) node(state int) (cell *SudokuCell, digit int) {
+	n := cg.sud.size()
+	node := state / 2
+	return cg.sud.cellAt(node / n), node%n + 1
+}
+
+// implications works out everything that follows from state through the
+// links, breadth first so each is reached by the shortest chain there is. A
+// candidate on turns every candidate weakly linked to it off, one off turns
+// every candidate strongly linked to it on, so the links alternate. Returns,
+// for every state, the one it was reached from: state itself for the start
+// and -1 for those not reached within maxChainLength links.
+func (cg *chainGraph) implications(state int) []int {
+	from := make([]int, 2*len(cg.strong))
+	for i := range from {
+		from[i] = -1
+	}
+	from[state] = state
+	level := []int{state}
+	for depth := 0; depth < maxChainLength && len(level) > 0; depth++ {
+		var next []int
+		for _, s := range level {
+			links, on := cg.strong[s/2], false
+			if s%2 == 1 {
+				links, on = cg.weak[s/2], true
+			}
+			for _, node := range links {
+				t := chainState(node, !on)
+				if from[t] < 0 {
+					from[t] = s
+					next = append(next, t)
+				}
+			}
+		}
+		level = next
+	}
+	return from
+}
+
+// The chain from the start of an implications search to state, in order.
+func chainTo(from []int, state int) []int {
+	chain := []int{state}
+	for from[state] != state {
+		state = from[state]
+		chain = append([]int{state}, chain...)
+	}
+	return chain
+}
+
+// Writes a chain out for a person to follow, as "0,0 != 1 -> 0,4 = 1 -> ...".
+func (cg *chainGraph) proof(chain []int) string {
+	parts := make([]string, len(chain))
+	for i, s := range chain {
+		cell, digit := cg.node(s)
+		op := "!="
+		if s%2 == 1 {
+			op = "="
+		}
+		parts[i] = fmt.Sprintf("%d,%d %s %d", cell.row, cell.col, op, digit)
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// Adds the cells of a chain to the step, each only once.
+func (cg *chainGraph) addChainCells(st *Step, chain []int) {
+	for _, s := range chain {
+		cell, _ := cg.node(s)
+		if !containsRef(st.Cells, CellRef{cell.row, cell.col}) {
+			st.addCell(cell)
+		}
+	}
+}
+
+func containsRef(refs []CellRef, ref CellRef) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// An Alternating Inference Chain runs from one candidate to another through
+// links that alternate strong, weak, strong, ..., strong. If the first is
+// false the chain makes the last true, so one or other of them is, and any
+// candidate weakly linked to both can go.
+//
+// A chain that gets back to where it started is a discontinuous nice loop:
+// false leading to true means the candidate is true, true leading to false
+// means it's false.
+func aic(sud *SudokuSquare, step StepFunc) (bool, error) {
+	changes := false
+	for {
+		st, err := findAIC(sud)
+		if err != nil {
+			return changes, err
+		}
+		if st == nil {
+			return changes, nil
+		}
+		changes = true
+		if e := report(step, *st); e != nil {
+			return true, e
+		}
+	}
+}
+
+// Finds and applies the first productive AIC or discontinuous nice loop.
+func findAIC(sud *SudokuSquare) (*Step, error) {
+	cg := newChainGraph(sud)
+	for x := range cg.strong {
+		start, digit := cg.node(chainState(x, false))
+		if !start.hasCandidate(digit) {
+			continue
+		}
+		from := cg.implications(chainState(x, false))
+
+		if on := chainState(x, true); from[on] >= 0 {
+			chain := chainTo(from, on)
+			st := Step{Technique: "Discontinuous Nice Loop", Proof: []string{cg.proof(chain)}}
+			cg.addChainCells(&st, chain)
+			if err := st.place(sud, start, digit); err != nil {
+				return nil, err
+			}
+			return &st, nil
+		}
+
+		for y := range cg.strong {
+			on := chainState(y, true)
+			if y == x || from[on] < 0 {
+				continue
+			}
+			chain := chainTo(from, on)
+			st := Step{Technique: "AIC", Proof: []string{cg.proof(chain)}}
+			cg.addChainCells(&st, chain)
+			for _, z := range cg.weak[x] {
+				if z != y && containsInt(cg.weak[y], z) {
+					cell, val := cg.node(chainState(z, false))
+					st.eliminate(cell, val)
+				}
+			}
+			if len(st.Eliminations) > 0 {
+				return &st, nil
+			}
+		}
+
+		from = cg.implications(chainState(x, true))
+		if off := chainState(x, false); from[off] >= 0 {
+			chain := chainTo(from, off)
+			st := Step{Technique: "Discontinuous Nice Loop", Proof: []string{cg.proof(chain)}}
+			cg.addChainCells(&st, chain)
+			st.eliminate(start, digit)
+			return &st, nil
+		}
+	}
+	return nil, nil
+}
+
+// A cell forcing chain tries each candidate left in a cell in turn. One of
+// them has to be true, so whatever follows from all of them holds.
+func cellForcingChain(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
+	cg := newChainGraph(sud)
+	changes := false
+	for idx := 0; idx < n*n; idx++ {
+		cell := sud.cellAt(idx)
+		if cell.isSet || bits.OnesCount32(cell.candidates) < 2 {
+			continue
+		}
+		var branches []int
+		for _, digit := range cellDigits(cell) {
+			branches = append(branches, idx*n+digit-1)
+		}
+		impacting, err := forcingChain(cg, step, "Cell Forcing Chain", "", branches)
+		changes = changes || impacting
+		if err != nil {
+			return changes, err
+		}
+		if impacting {
+			cg = newChainGraph(sud)
+		}
+	}
+	return changes, nil
+}
+
+// A house forcing chain tries each place left for a digit in a house in
+// turn. One of them has to hold the digit, so whatever follows from all of
+// them holds.
+func houseForcingChain(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
+	cg := newChainGraph(sud)
+	changes := false
+	for _, h := range sud.geo.houses {
+		for digit := 1; digit <= n; digit++ {
+			var branches []int
+			for _, idx := range h.cells {
+				if sud.cellAt(idx).hasCandidate(digit) {
+					branches = append(branches, idx*n+digit-1)
+				}
+			}
+			if len(branches) < 2 {
+				continue
+			}
+			impacting, err := forcingChain(cg, step, "House Forcing Chain", h.name, branches)
+			changes = changes || impacting
+			if err != nil {
+				return changes, err
+			}
+			if impacting {
+				cg = newChainGraph(sud)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Follows the chains from each of the branches, one of which has to be
+// true, and makes a step of each thing they all agree on, with a chain
+// from every branch as its proof. Leaves cg out of date if it changed
+// anything.
+func forcingChain(cg *chainGraph, step StepFunc, technique, house string, branches []int) (bool, error) {
+	sud := cg.sud
+	froms := make([][]int, len(branches))
+	for i, b := range branches {
+		froms[i] = cg.implications(chainState(b, true))
+	}
+	changes := false
+	for state := range froms[0] {
+		agreed := true
+		for _, from := range froms {
+			agreed = agreed && from[state] >= 0 && from[state] != state
+		}
+		if !agreed {
+			continue
+		}
+		cell, digit := cg.node(state)
+		if !cell.hasCandidate(digit) {
+			continue // already dealt with by an earlier step
+		}
+		st := Step{Technique: technique, House: house}
+		for i, b := range branches {
+			branch, _ := cg.node(chainState(b, true))
+			if !containsRef(st.Cells, CellRef{branch.row, branch.col}) {
+				st.addCell(branch)
+			}
+			st.Proof = append(st.Proof, cg.proof(chainTo(froms[i], state)))
+		}
+		if state%2 == 1 {
+			if err := st.place(sud, cell, digit); err != nil {
+				return changes, err
+			}
+		} else {
+			st.eliminate(cell, digit)
+		}
+		changes = true
+		if e := report(step, st); e != nil {
+			return true, e
+		}
+	}
+	return changes, nil
+}
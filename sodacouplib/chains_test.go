@@ -0,0 +1,87 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAIC(t *testing.T) {
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	keepOnly(s, 1, rowRefs(4), CellRef{4, 0}, CellRef{4, 4})
+
+	steps := collectSteps(t, aic, s)
+	assert.Equal(t, 2, len(steps))
+	assert.Equal(t, Step{
+		Technique: "AIC",
+		Cells:     []CellRef{{0, 0}, {0, 4}, {4, 4}, {4, 0}},
+		Eliminations: []Candidate{
+			{1, 0, 1}, {2, 0, 1}, {3, 0, 1}, {5, 0, 1}, {6, 0, 1}, {7, 0, 1}, {8, 0, 1},
+		},
+		Proof: []string{"0,0 != 1 -> 0,4 = 1 -> 4,4 != 1 -> 4,0 = 1"},
+	}, steps[0])
+	assert.Equal(t, []string{"0,4 != 1 -> 0,0 = 1 -> 4,0 != 1 -> 4,4 = 1"}, steps[1].Proof)
+	noOpCheck(t, aic, s)
+
+	t.Run("discontinuous loop places the value", func(t *testing.T) {
+		s := newEmptySudoku()
+		keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+		keepOnly(s, 1, rowRefs(4), CellRef{4, 4}, CellRef{4, 1})
+		box := []CellRef{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}
+		keepOnly(s, 1, box, CellRef{0, 0}, CellRef{1, 1})
+
+		assert.Equal(t, []Step{{
+			Technique:  "Discontinuous Nice Loop",
+			Cells:      []CellRef{{0, 0}, {0, 4}, {4, 4}, {4, 1}, {1, 1}},
+			Placements: []Candidate{{0, 0, 1}},
+			Proof:      []string{"0,0 != 1 -> 0,4 = 1 -> 4,4 != 1 -> 4,1 = 1 -> 1,1 != 1 -> 0,0 = 1"},
+		}}, collectSteps(t, aic, s))
+	})
+}
+
+func TestCellForcingChain(t *testing.T) {
+	// whichever of 1, 2 or 3 the middle cell is, the pair of bivalue cells
+	// make the other two
+	s := newEmptySudoku()
+	s.cells[4][4].candidates = 1<<1 | 1<<2 | 1<<3
+	s.cells[4][6].candidates = 1<<1 | 1<<2
+	s.cells[4][7].candidates = 1<<1 | 1<<3
+
+	steps := collectSteps(t, cellForcingChain, s)
+	assert.Equal(t, Step{
+		Technique:    "Cell Forcing Chain",
+		Cells:        []CellRef{{4, 4}},
+		Eliminations: []Candidate{{4, 0, 1}},
+		Proof: []string{
+			"4,4 = 1 -> 4,0 != 1",
+			"4,4 = 2 -> 4,6 != 2 -> 4,6 = 1 -> 4,0 != 1",
+			"4,4 = 3 -> 4,7 != 3 -> 4,7 = 1 -> 4,0 != 1",
+		},
+	}, steps[0])
+	// 1, 2 and 3 go from the rest of the row
+	assert.Equal(t, 6*3, len(steps))
+	noOpCheck(t, cellForcingChain, s)
+}
+
+func TestHouseForcingChain(t *testing.T) {
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4}, CellRef{0, 7})
+	keepOnly(s, 1, []CellRef{{6, 0}, {6, 1}, {6, 2}, {7, 0}, {7, 1}, {7, 2}, {8, 0}, {8, 1}, {8, 2}},
+		CellRef{7, 0}, CellRef{8, 2})
+	keepOnly(s, 1, []CellRef{{6, 6}, {6, 7}, {6, 8}, {7, 6}, {7, 7}, {7, 8}, {8, 6}, {8, 7}, {8, 8}},
+		CellRef{7, 7}, CellRef{8, 8})
+
+	steps := collectSteps(t, houseForcingChain, s)
+	assert.Equal(t, Step{
+		Technique:    "House Forcing Chain",
+		House:        "row 0",
+		Cells:        []CellRef{{0, 0}, {0, 4}, {0, 7}},
+		Eliminations: []Candidate{{8, 4, 1}},
+		Proof: []string{
+			"0,0 = 1 -> 7,0 != 1 -> 8,2 = 1 -> 8,4 != 1",
+			"0,4 = 1 -> 8,4 != 1",
+			"0,7 = 1 -> 7,7 != 1 -> 8,8 = 1 -> 8,4 != 1",
+		},
+	}, steps[1])
+	assert.Equal(t, Candidate{7, 4, 1}, steps[0].Eliminations[0])
+}
@@ -8,8 +8,6 @@ package sodacouplib
 import (
 	"log"
 	"math/rand"
-	"reflect"
-	"runtime"
 	"strings"
 	"time"
 	"unicode"
@@ -25,10 +23,6 @@ func removeWhitespace(s string) string {
 	}, s)
 }
 
-func getFunctionName(i interface{}) string {
-	return runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
-}
-
 func RandomSeed() {
 	seed := time.Now().UTC().UnixNano()
 	log.Println("using seed", seed)
@@ -0,0 +1,260 @@
+package sodacouplib
+
+// exactCover is a matrix of 0s and 1s for Knuth's Algorithm X, which looks
+// for a set of rows with exactly one 1 in each column between them. It's
+// kept as Dancing Links: the 1s are nodes in circular doubly linked lists,
+// along their row and down their column, so taking a column out of the
+// matrix along with every row that has a 1 in it (covering it) and putting
+// it all back again are only a few link changes each. Nodes are indexes
+// into the slices: 0 is the root, 1 up to the number of columns are the
+// column headers and the rest are the 1s.
+//
+// Primary columns have to be covered exactly once. Secondary ones have to
+// be covered at most once, which is how a constraint like "no value twice
+// in a cage" goes in.
+type exactCover struct {
+	left, right, up, down []int
+	// column is the header of each node's column and row the row it's in.
+	column, row []int
+	// size is how many 1s are left in each column, by header.
+	size []int
+	// rowNode is a node in each row.
+	rowNode []int
+	// try is asked before each row is taken, and can turn it down for
+	// reasons the columns can't express, such as a cage's sum. untry is
+	// told when a row try let in is put back. Either can be nil.
+	try   func(row int) bool
+	untry func(row int)
+}
+
+func newExactCover(primary, secondary int) *exactCover {
+	columns := primary + secondary
+	m := &exactCover{size: make([]int, columns+1)}
+	for h := 0; h <= columns; h++ {
+		m.left = append(m.left, h-1)
+		m.right = append(m.right, h+1)
+		m.up = append(m.up, h)
+		m.down = append(m.down, h)
+		m.column = append(m.column, h)
+		m.row = append(m.row, -1)
+	}
+	// only the primary headers are linked in with the root, so the search
+	// is done once they're all covered
+	m.left[0], m.right[primary] = primary, 0
+	for h := primary + 1; h <= columns; h++ {
+		m.left[h], m.right[h] = h, h
+	}
+	return m
+}
+
+// Adds a row with 1s in the given columns, numbered from 0 with the primary
+// ones first, and returns its number.
+func (m *exactCover) addRow(columns []int) int {
+	r := len(m.rowNode)
+	first := len(m.left)
+	for i, c := range columns {
+		h, node := c+1, first+i
+		m.left = append(m.left, node-1)
+		m.right = append(m.right, node+1)
+		m.up = append(m.up, m.up[h])
+		m.down = append(m.down, h)
+		m.down[m.up[h]] = node
+		m.up[h] = node
+		m.column = append(m.column, h)
+		m.row = append(m.row, r)
+		m.size[h]++
+	}
+	last := len(m.left) - 1
+	m.left[first], m.right[last] = last, first
+	m.rowNode = append(m.rowNode, first)
+	return r
+}
+
+// Takes a column out of the header list, and every row with a 1 in it out
+// of the other columns.
+func (m *exactCover) cover(h int) {
+	m.right[m.left[h]], m.left[m.right[h]] = m.right[h], m.left[h]
+	for i := m.down[h]; i != h; i = m.down[i] {
+		for j := m.right[i]; j != i; j = m.right[j] {
+			m.down[m.up[j]], m.up[m.down[j]] = m.down[j], m.up[j]
+			m.size[m.column[j]]--
+		}
+	}
+}
+
+// Undoes cover, in exactly the reverse order.
+func (m *exactCover) uncover(h int) {
+	for i := m.up[h]; i != h; i = m.up[i] {
+		for j := m.left[i]; j != i; j = m.left[j] {
+			m.size[m.column[j]]++
+			m.down[m.up[j]], m.up[m.down[j]] = j, j
+		}
+	}
+	m.right[m.left[h]], m.left[m.right[h]] = h, h
+}
+
+// Takes out a primary column that's already covered some other way (by a
+// given, say) and so mustn't be again. It should have no 1s left.
+func (m *exactCover) drop(c int) {
+	m.cover(c + 1)
+}
+
+// search calls found with each set of rows that covers the matrix, until it
+// returns false. Reports whether it ran out of sets rather than being
+// stopped.
+func (m *exactCover) search(found func(rows []int) bool) bool {
+	return m.searchFrom(nil, found)
+}
+
+func (m *exactCover) searchFrom(chosen []int, found func(rows []int) bool) bool {
+	if m.right[0] == 0 {
+		return found(chosen)
+	}
+	// branch on the column with fewest ways to cover it
+	best := m.right[0]
+	for h := m.right[best]; h != 0 && m.size[best] > 0; h = m.right[h] {
+		if m.size[h] < m.size[best] {
+			best = h
+		}
+	}
+	if m.size[best] == 0 {
+		return true // dead end
+	}
+	m.cover(best)
+	more := true
+	for i := m.down[best]; i != best && more; i = m.down[i] {
+		r := m.row[i]
+		if m.try != nil && !m.try(r) {
+			continue
+		}
+		for j := m.right[i]; j != i; j = m.right[j] {
+			m.cover(m.column[j])
+		}
+		more = m.searchFrom(append(chosen, r), found)
+		for j := m.left[i]; j != i; j = m.left[j] {
+			m.uncover(m.column[j])
+		}
+		if m.untry != nil {
+			m.untry(r)
+		}
+	}
+	m.uncover(best)
+	return more
+}
+
+// sudokuCover is a square as an exact cover problem. There's a row for each
+// candidate left in an unset cell, with a 1 in the primary column for the
+// cell being filled and in the one for the value being in each house the
+// cell is in, and one in the secondary column for the value being in the
+// cell's cage. Variants with houses of their own, diagonals or jigsaw
+// regions say, need nothing more, and a constraint that can't be put as
+// columns (cage sums) goes through try and untry.
+type sudokuCover struct {
+	*exactCover
+	cells grid
+	// the cell (row*size+col) and value for each row of the matrix
+	rowCell, rowValue []int
+	// budget has a node taken out of it for each row tried. Once it's run
+	// out no more are, and err says why.
+	budget *budget
+	err    error
+}
+
+// Builds the matrix for the square, or errors if its givens already clash.
+func newSudokuCover(sud *SudokuSquare, b *budget) (*sudokuCover, error) {
+	geo := sud.geo
+	n := geo.size
+	primary := n*n + len(geo.houses)*n
+	sc := &sudokuCover{
+		exactCover: newExactCover(primary, len(geo.cages)*n),
+		cells:      copyFrom(sud),
+		budget:     b,
+	}
+	// the columns for each value going in the cell
+	columns := func(idx, val int) []int {
+		cols := []int{idx}
+		for _, h := range geo.cellHouses[idx] {
+			cols = append(cols, n*n+h*n+val-1)
+		}
+		if c := geo.cellCage[idx]; c >= 0 {
+			cols = append(cols, primary+c*n+val-1)
+		}
+		return cols
+	}
+	filled := make([]bool, primary+len(geo.cages)*n)
+	for idx, val := range sc.cells.cells {
+		if !isSet(val) {
+			continue
+		}
+		for _, c := range columns(idx, int(val)) {
+			if filled[c] {
+				return nil, duplicateClue(sud, idx, int(val))
+			}
+			filled[c] = true
+		}
+	}
+	for idx, val := range sc.cells.cells {
+		if isSet(val) {
+			continue
+		}
+		for _, val := range cellDigits(sud.cellAt(idx)) {
+			cols := columns(idx, val)
+			clash := false
+			for _, c := range cols {
+				clash = clash || filled[c]
+			}
+			if !clash {
+				sc.addRow(cols)
+				sc.rowCell = append(sc.rowCell, idx)
+				sc.rowValue = append(sc.rowValue, val)
+			}
+		}
+	}
+	for c := 0; c < primary; c++ {
+		if filled[c] {
+			sc.drop(c)
+		}
+	}
+	sc.try = func(r int) bool {
+		if sc.err != nil {
+			return false
+		}
+		if sc.err = sc.budget.spend(); sc.err != nil {
+			return false
+		}
+		idx, val := sc.rowCell[r], sc.rowValue[r]
+		if !cageAllows(sc.cells, idx, val) {
+			return false
+		}
+		sc.cells.cells[idx] = byte(val)
+		return true
+	}
+	sc.untry = func(r int) {
+		sc.cells.cells[sc.rowCell[r]] = 0
+	}
+	return sc, nil
+}
+
+// dancingLinks finds a solution for the square with Algorithm X, which
+// unlike backTrack always branches wherever there are fewest options. That
+// makes it a lot quicker on puzzles with few givens. Like backTrack it
+// doesn't check the solution is unique. Each row tried is a node of b.
+func dancingLinks(sud *SudokuSquare, b *budget) (bool, error) {
+	sc, err := newSudokuCover(sud, b)
+	if err != nil {
+		return false, err
+	}
+	var solution grid
+	sc.search(func(rows []int) bool {
+		solution = sc.cells.clone()
+		return false
+	})
+	if sc.err != nil {
+		return false, sc.err
+	}
+	if solution.cells == nil {
+		return false, errNoSolution()
+	}
+	copyTo(solution, sud)
+	return false, nil
+}
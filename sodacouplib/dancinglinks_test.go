@@ -0,0 +1,146 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// 17 givens, about as few as a puzzle with one solution can have, which
+// backTrack takes seconds over.
+const seventeenClues = `
+	___ ___ _1_
+	4__ ___ ___
+	_2_ ___ ___
+
+	___ _5_ 4_7
+	__8 ___ 3__
+	__1 _9_ ___
+
+	3__ 4__ 2__
+	_5_ 1__ ___
+	___ 8_6 ___
+`
+
+func TestExactCover(t *testing.T) {
+	// Knuth's example from the Dancing Links paper
+	m := newExactCover(7, 0)
+	for _, row := range [][]int{{2, 4, 5}, {0, 3, 6}, {1, 2, 5}, {0, 3}, {1, 6}, {3, 4, 6}} {
+		m.addRow(row)
+	}
+	var solutions [][]int
+	assert.Equal(t, true, m.search(func(rows []int) bool {
+		solutions = append(solutions, append([]int(nil), rows...))
+		return true
+	}))
+	assert.Equal(t, [][]int{{3, 0, 4}}, solutions)
+
+	t.Run("secondary columns", func(t *testing.T) {
+		// the second column needn't be covered, but not twice either
+		m := newExactCover(2, 1)
+		m.addRow([]int{0, 2})
+		m.addRow([]int{1, 2})
+		m.addRow([]int{1})
+		solutions = nil
+		m.search(func(rows []int) bool {
+			solutions = append(solutions, append([]int(nil), rows...))
+			return true
+		})
+		assert.Equal(t, [][]int{{0, 2}}, solutions)
+	})
+	t.Run("stops when asked", func(t *testing.T) {
+		m := newExactCover(1, 0)
+		m.addRow([]int{0})
+		m.addRow([]int{0})
+		count := 0
+		assert.Equal(t, false, m.search(func(rows []int) bool {
+			count++
+			return false
+		}))
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestDancingLinks(t *testing.T) {
+	t.Run("few givens", func(t *testing.T) {
+		s, _ := NewSudokuSquare(seventeenClues)
+		if _, err := dancingLinks(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		assertSolution(t, s, `
+			693 784 512
+			487 512 936
+			125 963 874
+
+			932 651 487
+			568 247 391
+			741 398 625
+
+			319 475 268
+			856 129 743
+			274 836 159
+		`)
+	})
+	t.Run("no solution", func(t *testing.T) {
+		s, _ := NewSudokuSquare(`
+			1_3 456 729
+			426 789 1_3
+			789 123 456
+
+			214 365 897
+			365 897 214
+			897 214 365
+
+			531 642 978
+			642 978 531
+			978 531 642
+		`)
+		_, err := dancingLinks(s, nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to converge")
+	})
+	t.Run("cages", func(t *testing.T) {
+		cages, _ := ParseCages(killerCages)
+		s, _ := NewSudokuSquareWithVariant(killerProblem, ClassicLayout, Variant{Cages: cages})
+		if _, err := dancingLinks(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		assertSolution(t, s, fullGrid)
+	})
+	t.Run("diagonals", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Diagonals: true})
+		if _, err := dancingLinks(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		diag, anti := map[byte]bool{}, map[byte]bool{}
+		for i := 0; i < 9; i++ {
+			diag[s.cells[i][i].value] = true
+			anti[s.cells[i][8-i].value] = true
+		}
+		assert.Equal(t, 9, len(diag))
+		assert.Equal(t, 9, len(anti))
+	})
+	t.Run("other sizes", func(t *testing.T) {
+		layout, _ := LayoutForSize(16)
+		s, _ := NewSudokuSquareWithLayout(patternGrid(layout, 3), layout)
+		if _, err := dancingLinks(s, nil); err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		expected, _ := NewSudokuSquareWithLayout(patternGrid(layout, 0), layout)
+		assert.Equal(t, expected.String(), s.String())
+	})
+}
+
+func TestSolveBackend(t *testing.T) {
+	none, _ := NewStrategySet()
+	s, _ := NewSudokuSquare(seventeenClues)
+	steps, err := s.Solve(SolveOptions{Strategies: none, Backend: DancingLinks})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, "Backtracking", steps[0].Technique)
+	assert.Equal(t, 81-17, len(steps[0].Placements))
+	assert.Equal(t, true, isSolved(s))
+}
+
+func BenchmarkDancingLinks(b *testing.B) {
+	benchmarkSolver(b, dancingLinks)
+}
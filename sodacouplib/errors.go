@@ -0,0 +1,131 @@
+package sodacouplib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The kinds of error the package returns, for telling them apart with
+// errors.Is. The errors themselves say more: a *CellError which cells,
+// values and houses are to blame, a *ParseError where in the input.
+var (
+	// ErrContradiction is the square getting into a state no solution can
+	// come from, such as a cell with no candidates left or a house with
+	// nowhere left for a value.
+	ErrContradiction = errors.New("contradiction")
+	// ErrNoSolution is a search for a solution running out of things to
+	// try.
+	ErrNoSolution = errors.New("no solution")
+	// ErrDuplicateClue is a value given twice in a house or cage.
+	ErrDuplicateClue = errors.New("duplicate clue")
+	// ErrParse is input that can't be read.
+	ErrParse = errors.New("parse error")
+	// ErrOutOfRange is a row, column or value that isn't on the board.
+	ErrOutOfRange = errors.New("out of range")
+	// ErrInvalidVariant is a Layout or Variant that doesn't make a board,
+	// such as a size that can't be split into boxes, overlapping cages or a
+	// jigsaw region of the wrong size.
+	ErrInvalidVariant = errors.New("invalid variant")
+	// ErrNotUnique is a problem with more than one solution where only one
+	// will do.
+	ErrNotUnique = errors.New("more than one solution")
+)
+
+// CellError is an error about some part of the square, so the cell, value
+// and house it's about can be picked out. It unwraps to its Kind.
+type CellError struct {
+	// Kind is one of the Err values above, other than ErrParse.
+	Kind error
+	// Row and Col are the cell it's about, both -1 if it's not about one
+	// cell.
+	Row, Col int
+	// Value is the value it's about, 0 if it's not about one value.
+	Value int
+	// House is the name of the house (or cage) it's about, "row 3" or
+	// "block 0 2" say, "" if it's not about one house.
+	House string
+	msg   string
+}
+
+func (e *CellError) Error() string {
+	return e.msg
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Kind
+}
+
+// A CellError about a cell, with the value and house it's about if any.
+func cellError(kind error, row, col, val int, house string, format string, args ...interface{}) *CellError {
+	return &CellError{kind, row, col, val, house, fmt.Sprintf(format, args...)}
+}
+
+// A CellError about a house (or cage) as a whole, and the value it's about
+// if any.
+func houseError(kind error, house string, val int, format string, args ...interface{}) *CellError {
+	return cellError(kind, -1, -1, val, house, format, args...)
+}
+
+// The error for a search that couldn't find a solution.
+func errNoSolution() *CellError {
+	return houseError(ErrNoSolution, "", 0, "failed to converge")
+}
+
+// The error for val given in the cell at idx when another cell in one of its
+// houses (or its cage) already has it, or nil if none has.
+func duplicateClue(sud *SudokuSquare, idx, val int) error {
+	geo := sud.geo
+	houses := func(fn func(name string, cells []int) error) error {
+		for _, h := range geo.cellHouses[idx] {
+			if err := fn(geo.houses[h].name, geo.houses[h].cells); err != nil {
+				return err
+			}
+		}
+		if c := geo.cellCage[idx]; c >= 0 {
+			return fn(geo.cages[c].name, geo.cages[c].cells)
+		}
+		return nil
+	}
+	return houses(func(name string, cells []int) error {
+		for _, i := range cells {
+			if cell := sud.cellAt(i); i != idx && cell.isSet && int(cell.value) == val {
+				row, col := idx/geo.size, idx%geo.size
+				return cellError(ErrDuplicateClue, row, col, val, name,
+					"%d given at %d,%d is already at %d,%d in %s", val, row, col, cell.row, cell.col, name)
+			}
+		}
+		return nil
+	})
+}
+
+// ParseError is input that couldn't be read, and where it went wrong. It
+// unwraps to ErrParse.
+type ParseError struct {
+	// Line and Column are where in the input it went wrong, counting from
+	// 1. Column is 0 if it's about a whole line, and both are if it's about
+	// the input as a whole (being the wrong length, say).
+	Line, Column int
+	msg          string
+}
+
+func (e *ParseError) Error() string {
+	switch {
+	case e.Column > 0:
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.msg)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.msg)
+	}
+	return e.msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrParse
+}
+
+// A ParseError at the byte offset into s.
+func parseErrorAt(s string, offset int, format string, args ...interface{}) *ParseError {
+	line := strings.Count(s[:offset], "\n") + 1
+	col := offset - strings.LastIndex(s[:offset], "\n")
+	return &ParseError{line, col, fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,168 @@
+package sodacouplib
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	t.Run("value too big for the board", func(t *testing.T) {
+		_, err := NewSudokuSquare("123 456 789\n456 789 123\n789 103 456\n" + emptyClassic[27:])
+		var parse *ParseError
+		assert.Equal(t, true, errors.As(err, &parse))
+		assert.Equal(t, true, errors.Is(err, ErrParse))
+		assert.Equal(t, 3, parse.Line)
+		assert.Equal(t, 6, parse.Column)
+		assert.Equal(t, `line 3, column 6: '0' isn't a value on a 9x9 board`, err.Error())
+	})
+	t.Run("wrong length", func(t *testing.T) {
+		_, err := NewSudokuSquare("123")
+		var parse *ParseError
+		assert.Equal(t, true, errors.As(err, &parse))
+		assert.Equal(t, 0, parse.Line)
+		assert.Equal(t, "doesn't look like a valid sudoku", err.Error())
+	})
+	t.Run("cages", func(t *testing.T) {
+		_, err := ParseCages("3: 0,0 0,1\n4: 0,2 0;3")
+		assert.Equal(t, &ParseError{Line: 2, msg: `bad cell "0;3"`}, err)
+		assert.Equal(t, `line 2: bad cell "0;3"`, err.Error())
+
+		_, err = ParseCages("[{\"sum\": 3,\n \"cells\": [[0, 0] [0, 1]]}]")
+		var parse *ParseError
+		assert.Equal(t, true, errors.As(err, &parse))
+		assert.Equal(t, 2, parse.Line)
+	})
+}
+
+func TestDuplicateClue(t *testing.T) {
+	_, err := NewSudokuSquare("5__ ___ 5__" + emptyClassic[9:])
+	var cellErr *CellError
+	assert.Equal(t, true, errors.As(err, &cellErr))
+	assert.Equal(t, true, errors.Is(err, ErrDuplicateClue))
+	assert.Equal(t, 0, cellErr.Row)
+	assert.Equal(t, 6, cellErr.Col)
+	assert.Equal(t, 5, cellErr.Value)
+	assert.Equal(t, "row 0", cellErr.House)
+	assert.Equal(t, "5 given at 0,6 is already at 0,0 in row 0", err.Error())
+
+	t.Run("in a cage", func(t *testing.T) {
+		cages := []Cage{{3, []CellRef{{0, 0}, {4, 4}}}}
+		_, err := NewSudokuSquareWithVariant("1"+emptyClassic[1:40]+"1"+emptyClassic[41:], ClassicLayout, Variant{Cages: cages})
+		assert.Equal(t, true, errors.As(err, &cellErr))
+		assert.Equal(t, "cage 0", cellErr.House)
+		assert.Equal(t, CellRef{4, 4}, CellRef{cellErr.Row, cellErr.Col})
+	})
+}
+
+func TestContradiction(t *testing.T) {
+	s := newEmptySudoku()
+	assert.NoError(t, s.setCell(0, 0, 7))
+	err := s.setCell(0, 8, 7)
+	assert.Equal(t, true, errors.Is(err, ErrContradiction))
+	var cellErr *CellError
+	assert.Equal(t, true, errors.As(err, &cellErr))
+	assert.Equal(t, CellError{ErrContradiction, 0, 8, 7, "", "trying to add 7 to 0,8"}, *cellErr)
+	err = s.setCell(0, 0, 7)
+	assert.Equal(t, true, errors.As(err, &cellErr))
+	assert.Equal(t, CellRef{0, 0}, CellRef{cellErr.Row, cellErr.Col})
+
+	// nowhere left for a 3 in column 2
+	for row := 0; row < 9; row++ {
+		s.cells[row][2].removeCandidate(3)
+	}
+	_, err = sanityCheck(s)
+	assert.Equal(t, true, errors.As(err, &cellErr))
+	assert.Equal(t, ErrContradiction, cellErr.Kind)
+	assert.Equal(t, "column 2", cellErr.House)
+	assert.Equal(t, 3, cellErr.Value)
+	assert.Equal(t, -1, cellErr.Row)
+}
+
+func TestInvalidVariant(t *testing.T) {
+	var cellErr *CellError
+	cages := []Cage{{3, []CellRef{{0, 0}, {0, 1}}}, {4, []CellRef{{0, 1}, {0, 2}}}}
+	_, err := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Cages: cages})
+	assert.Equal(t, true, errors.As(err, &cellErr))
+	assert.Equal(t, ErrInvalidVariant, cellErr.Kind)
+	assert.Equal(t, CellRef{0, 1}, CellRef{cellErr.Row, cellErr.Col})
+	assert.Equal(t, "cage 1", cellErr.House)
+
+	cages = []Cage{{3, []CellRef{{0, 0}, {0, 9}}}}
+	_, err = NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Cages: cages})
+	assert.Equal(t, true, errors.Is(err, ErrOutOfRange))
+
+	regions, _ := ParseRegions(`
+		AAABBBCCC
+		AAABBBCCC
+		ABABBBCCC
+		DDDEEEFFF
+		DDDEEEFFF
+		DDDEEEFFF
+		GGGHHHIII
+		GGGHHHIII
+		GGGHHHIII
+	`)
+	_, err = NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Regions: regions})
+	assert.Equal(t, true, errors.As(err, &cellErr))
+	assert.Equal(t, ErrInvalidVariant, cellErr.Kind)
+	assert.Equal(t, "region 0", cellErr.House)
+}
+
+func TestNoSolution(t *testing.T) {
+	s, _ := NewSudokuSquare(`
+		1_3 456 729
+		426 789 1_3
+		789 123 456
+
+		214 365 897
+		365 897 214
+		897 214 365
+
+		531 642 978
+		642 978 531
+		978 531 642
+	`)
+	for _, solve := range []func(*SudokuSquare, *budget) (bool, error){backTrack, fastBackTrack, dancingLinks} {
+		_, err := solve(s.clone(), nil)
+		assert.Equal(t, true, errors.Is(err, ErrNoSolution))
+		assert.Equal(t, false, errors.Is(err, ErrContradiction))
+	}
+
+	t.Run("strategies never settling is a bug, not the puzzle", func(t *testing.T) {
+		err := untilTrue(func() (bool, error) { return false, nil })
+		assert.Error(t, err)
+		var cellErr *CellError
+		assert.Equal(t, false, errors.As(err, &cellErr))
+	})
+}
+
+func TestLayoutError(t *testing.T) {
+	_, err := NewSudokuSquareWithLayout(emptyClassic, Layout{0, 3})
+	assert.Equal(t, true, errors.Is(err, ErrOutOfRange))
+	_, err = LayoutForSize(26)
+	assert.Equal(t, true, errors.Is(err, ErrOutOfRange))
+	_, err = LayoutForSize(7)
+	assert.Equal(t, true, errors.Is(err, ErrInvalidVariant))
+	assert.Equal(t, "size 7 can't be split into boxes", err.Error())
+}
+
+func TestMinimizeError(t *testing.T) {
+	err := Minimize(newEmptySudoku())
+	assert.Equal(t, true, errors.Is(err, ErrNotUnique))
+
+	// nowhere left for a 1 in the top row
+	s := newEmptySudoku()
+	for col := 0; col < 9; col++ {
+		s.cells[0][col].removeCandidate(1)
+	}
+	err = Minimize(s)
+	assert.Equal(t, true, errors.Is(err, ErrNoSolution))
+}
+
+func TestFormatError(t *testing.T) {
+	_, err := FormatSudoku("123")
+	var parse *ParseError
+	assert.Equal(t, true, errors.As(err, &parse))
+	assert.Equal(t, "invalid length", err.Error())
+}
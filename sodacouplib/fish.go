@@ -0,0 +1,310 @@
+package sodacouplib
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Fish are named after how many lines they take.
+var fishNames = map[int]string{
+	2: "X-Wing",
+	3: "Swordfish",
+	4: "Jellyfish",
+}
+
+// if two rows have identical two remaining cells for a candidate then
+// they form a square that removes that candidate from the perpendicular columns
+// (and vice/versa for columns).
+func xWing(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return basicFish(sud, step, 2)
+}
+
+// xWing with three rows spread over three columns.
+func swordfish(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return basicFish(sud, step, 3)
+}
+
+// xWing with four rows spread over four columns.
+func jellyfish(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return basicFish(sud, step, 4)
+}
+
+// A fish looks along its base lines (rows, say) for a value, and removes it
+// from the lines across them (columns).
+type fishLines struct {
+	base string
+	// cell at position `pos` along base line `line`
+	cell func(sud *SudokuSquare, line, pos int) *SudokuCell
+}
+
+var fishDirections = []fishLines{
+	{"rows", func(sud *SudokuSquare, line, pos int) *SudokuCell {
+		return &sud.cells[line][pos]
+	}},
+	{"columns", func(sud *SudokuSquare, line, pos int) *SudokuCell {
+		return &sud.cells[pos][line]
+	}},
+}
+
+// If `size` rows between them only have a value in `size` columns, then the
+// value has to go in those columns on those rows, so can be removed from the
+// rest of those columns (and vice/versa for columns).
+func basicFish(sud *SudokuSquare, step StepFunc, size int) (bool, error) {
+	n := sud.size()
+	changes := false
+	for _, dir := range fishDirections {
+		for val := 1; val <= n; val++ {
+			masks := fishMasks(sud, dir, val)
+			err := eachFish(masks, size, func(base []int, cover uint32) error {
+				st := Step{Technique: fishNames[size], House: lineNames(dir.base, base)}
+				impacting := false
+				for pos := 0; pos < n; pos++ {
+					if cover&(1<<pos) == 0 {
+						continue
+					}
+					for _, line := range base {
+						if cell := dir.cell(sud, line, pos); cell.hasCandidate(val) {
+							st.addCell(cell)
+						}
+					}
+					for line := 0; line < n; line++ {
+						if !containsInt(base, line) {
+							impacting = st.eliminate(dir.cell(sud, line, pos), val) || impacting
+						}
+					}
+				}
+				if impacting {
+					changes = true
+					return report(step, st)
+				}
+				return nil
+			})
+			if err != nil {
+				return true, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// For each base line, the positions along it that still have val as a
+// candidate.
+func fishMasks(sud *SudokuSquare, dir fishLines, val int) []uint32 {
+	n := sud.size()
+	masks := make([]uint32, n)
+	for line := 0; line < n; line++ {
+		for pos := 0; pos < n; pos++ {
+			if dir.cell(sud, line, pos).hasCandidate(val) {
+				masks[line] |= 1 << pos
+			}
+		}
+	}
+	return masks
+}
+
+// Calls fn for every set of `size` base lines whose candidates all fall in
+// `size` cover lines. Lines with a single candidate are hidden singles, so
+// aren't counted.
+func eachFish(masks []uint32, size int, fn func(base []int, cover uint32) error) error {
+	base := make([]int, 0, size)
+	var recurse func(from int, cover uint32) error
+	recurse = func(from int, cover uint32) error {
+		if len(base) == size {
+			if bits.OnesCount32(cover) < size {
+				return nil // too few places for the value, sanityCheck's problem
+			}
+			return fn(base, cover)
+		}
+		for line := from; line < len(masks); line++ {
+			c := bits.OnesCount32(masks[line])
+			if c < 2 || c > size {
+				continue
+			}
+			next := cover | masks[line]
+			if bits.OnesCount32(next) > size {
+				continue
+			}
+			base = append(base, line)
+			if err := recurse(line+1, next); err != nil {
+				return err
+			}
+			base = base[:len(base)-1]
+		}
+		return nil
+	}
+	return recurse(0, 0)
+}
+
+// xWing where one of the rows also has the value in a box (the fin) off the
+// two columns.
+func finnedXWing(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return finnedFish(sud, step, 2)
+}
+
+func finnedSwordfish(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return finnedFish(sud, step, 3)
+}
+
+func finnedJellyfish(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return finnedFish(sud, step, 4)
+}
+
+// A finned fish is a basic fish with extra candidates (fins) on its rows
+// outside its columns, all inside one box. Either a fin is the value, or the
+// fish without its fins is a proper one; either way the value can go from
+// the cells in the fish's columns that are in the fins' box. A sashimi fish
+// is one where a row only has a single candidate in the columns, so without
+// the fins it wouldn't be a fish at all. Boxes are regions in jigsaw sudoku.
+func finnedFish(sud *SudokuSquare, step StepFunc, size int) (bool, error) {
+	n := sud.size()
+	changes := false
+	for _, dir := range fishDirections {
+		for val := 1; val <= n; val++ {
+			masks := fishMasks(sud, dir, val)
+			err := eachLineSet(masks, size, func(base []int) error {
+				for b := range sud.geo.blocks {
+					for _, cover := range finnedCovers(sud, dir, masks, base, b, size) {
+						impacting, err := finnedFishStep(sud, step, dir, val, masks, base, b, cover)
+						changes = changes || impacting
+						if err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return true, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// The ways of picking `size` cover positions for the base lines that leave
+// at least one candidate off them, with all those (the fins) in box b.
+func finnedCovers(sud *SudokuSquare, dir fishLines, masks []uint32, base []int, b, size int) []uint32 {
+	n := sud.size()
+	var inside, outside uint32
+	for _, line := range base {
+		for pos := 0; pos < n; pos++ {
+			if masks[line]&(1<<pos) == 0 {
+				continue
+			}
+			cell := dir.cell(sud, line, pos)
+			if sud.geo.cellBlock[cell.row*n+cell.col] == b {
+				inside |= 1 << pos
+			} else {
+				outside |= 1 << pos
+			}
+		}
+	}
+	// everything outside the box has to be covered, the rest of the cover
+	// comes from the positions inside it.
+	var covers []uint32
+	eachSubset(inside&^outside, size-bits.OnesCount32(outside), func(extra uint32) {
+		cover := outside | extra
+		if inside&^cover != 0 {
+			covers = append(covers, cover)
+		}
+	})
+	return covers
+}
+
+func finnedFishStep(sud *SudokuSquare, step StepFunc, dir fishLines, val int, masks []uint32, base []int, b int, cover uint32) (bool, error) {
+	n := sud.size()
+	kind := "Finned "
+	for _, line := range base {
+		if bits.OnesCount32(masks[line]&cover) < 2 {
+			kind = "Sashimi "
+		}
+	}
+	st := Step{Technique: kind + fishNames[len(base)], House: lineNames(dir.base, base)}
+	for pos := 0; pos < n; pos++ {
+		for _, line := range base {
+			if masks[line]&(1<<pos) != 0 {
+				st.addCell(dir.cell(sud, line, pos))
+			}
+		}
+	}
+	for pos := 0; pos < n; pos++ {
+		if cover&(1<<pos) == 0 {
+			continue
+		}
+		for line := 0; line < n; line++ {
+			cell := dir.cell(sud, line, pos)
+			if !containsInt(base, line) && sud.geo.cellBlock[cell.row*n+cell.col] == b {
+				st.eliminate(cell, val)
+			}
+		}
+	}
+	if len(st.Eliminations) == 0 {
+		return false, nil
+	}
+	return true, report(step, st)
+}
+
+// Calls fn for every set of `size` base lines that have the value somewhere.
+func eachLineSet(masks []uint32, size int, fn func(base []int) error) error {
+	base := make([]int, 0, size)
+	var recurse func(from int) error
+	recurse = func(from int) error {
+		if len(base) == size {
+			return fn(base)
+		}
+		for line := from; line < len(masks); line++ {
+			if masks[line] == 0 {
+				continue
+			}
+			base = append(base, line)
+			if err := recurse(line + 1); err != nil {
+				return err
+			}
+			base = base[:len(base)-1]
+		}
+		return nil
+	}
+	return recurse(0)
+}
+
+// Calls fn with every subset of k of the bits in mask.
+func eachSubset(mask uint32, k int, fn func(uint32)) {
+	if k < 0 || bits.OnesCount32(mask) < k {
+		return
+	}
+	if k == 0 {
+		fn(0)
+		return
+	}
+	low := mask & -mask
+	eachSubset(mask&^low, k-1, func(sub uint32) { fn(sub | low) })
+	eachSubset(mask&^low, k, fn)
+}
+
+// Names a set of lines like "rows 1, 4 & 7".
+func lineNames(kind string, lines []int) string {
+	var sb strings.Builder
+	sb.WriteString(kind)
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			sb.WriteByte(' ')
+		case i == len(lines)-1:
+			sb.WriteString(" & ")
+		default:
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%d", line)
+	}
+	return sb.String()
+}
+
+func containsInt(list []int, i int) bool {
+	for _, l := range list {
+		if l == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSwordfish(t *testing.T) {
+	t.Run("row based swordfish", func(t *testing.T) {
+		s := newEmptySudoku()
+		N := arbitraryValue()
+
+		// rows 1, 4 and 7 only have N in columns 0, 3 and 6, two each
+		for col := 0; col < 9; col++ {
+			if col != 0 && col != 3 {
+				s.cells[1][col].removeCandidate(N)
+			}
+			if col != 3 && col != 6 {
+				s.cells[4][col].removeCandidate(N)
+			}
+			if col != 0 && col != 6 {
+				s.cells[7][col].removeCandidate(N)
+			}
+		}
+
+		var steps []Step
+		_, err := swordfish(s, func(st Step) bool {
+			steps = append(steps, st)
+			return true
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(steps))
+		assert.Equal(t, "Swordfish", steps[0].Technique)
+		assert.Equal(t, "rows 1, 4 & 7", steps[0].House)
+		assert.Equal(t, []CellRef{{1, 0}, {7, 0}, {1, 3}, {4, 3}, {4, 6}, {7, 6}}, steps[0].Cells)
+
+		for row := 0; row < 9; row++ {
+			if row != 1 && row != 4 && row != 7 {
+				for _, col := range []int{0, 3, 6} {
+					assert.Equal(t, false, s.cells[row][col].hasCandidate(N))
+				}
+			}
+		}
+		noOpCheck(t, swordfish, s)
+	})
+	t.Run("col based swordfish", func(t *testing.T) {
+		s := newEmptySudoku()
+		N := arbitraryValue()
+
+		// columns 2, 5 and 8 only have N in rows 0, 1 and 2
+		for row := 3; row < 9; row++ {
+			for _, col := range []int{2, 5, 8} {
+				s.cells[row][col].removeCandidate(N)
+			}
+		}
+		s.cells[0][2].removeCandidate(N)
+		s.cells[1][5].removeCandidate(N)
+		s.cells[2][8].removeCandidate(N)
+
+		apply(t, swordfish, s)
+
+		for row := 0; row < 3; row++ {
+			for col := 0; col < 9; col++ {
+				if col != 2 && col != 5 && col != 8 {
+					assert.Equal(t, false, s.cells[row][col].hasCandidate(N))
+				}
+			}
+		}
+		noOpCheck(t, swordfish, s)
+	})
+	t.Run("needs the lines to share columns", func(t *testing.T) {
+		s := newEmptySudoku()
+		N := arbitraryValue()
+		for col := 0; col < 9; col++ {
+			if col != 0 && col != 3 {
+				s.cells[1][col].removeCandidate(N)
+			}
+			if col != 3 && col != 6 {
+				s.cells[4][col].removeCandidate(N)
+			}
+			if col != 0 && col != 7 {
+				s.cells[7][col].removeCandidate(N)
+			}
+		}
+		noOpCheck(t, swordfish, s)
+	})
+}
+
+func TestJellyfish(t *testing.T) {
+	s := newEmptySudoku()
+	N := arbitraryValue()
+
+	// rows 0, 2, 4 and 6 only have N in columns 1, 3, 5 and 7
+	for _, row := range []int{0, 2, 4, 6} {
+		for col := 0; col < 9; col++ {
+			if col%2 == 0 || col == row+1 {
+				s.cells[row][col].removeCandidate(N)
+			}
+		}
+	}
+	noOpCheck(t, swordfish, s)
+
+	var steps []Step
+	_, err := jellyfish(s, func(st Step) bool {
+		steps = append(steps, st)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, "Jellyfish", steps[0].Technique)
+	assert.Equal(t, "rows 0, 2, 4 & 6", steps[0].House)
+	for _, row := range []int{1, 3, 5, 7, 8} {
+		for _, col := range []int{1, 3, 5, 7} {
+			assert.Equal(t, false, s.cells[row][col].hasCandidate(N))
+		}
+	}
+	noOpCheck(t, jellyfish, s)
+}
+
+func TestLineNames(t *testing.T) {
+	assert.Equal(t, "rows 1 & 4", lineNames("rows", []int{1, 4}))
+	assert.Equal(t, "columns 0, 3 & 8", lineNames("columns", []int{0, 3, 8}))
+}
+
+func TestFinnedFish(t *testing.T) {
+	collect := func(fn sudokuAlgo, s *SudokuSquare) []Step {
+		var steps []Step
+		_, err := fn(s, func(st Step) bool {
+			steps = append(steps, st)
+			return true
+		})
+		assert.NoError(t, err)
+		return steps
+	}
+	t.Run("finned x-wing", func(t *testing.T) {
+		s := newEmptySudoku()
+		N := arbitraryValue()
+
+		// row 1 only has N in columns 1 and 7, row 7 has those and a fin
+		// at 7,8 in the bottom right box
+		for col := 0; col < 9; col++ {
+			if col != 1 && col != 7 {
+				s.cells[1][col].removeCandidate(N)
+			}
+			if col != 1 && col != 7 && col != 8 {
+				s.cells[7][col].removeCandidate(N)
+			}
+		}
+		noOpCheck(t, xWing, s)
+
+		steps := collect(finnedXWing, s)
+		assert.Equal(t, 1, len(steps))
+		assert.Equal(t, "Finned X-Wing", steps[0].Technique)
+		assert.Equal(t, "rows 1 & 7", steps[0].House)
+		assert.Equal(t, []CellRef{{1, 1}, {7, 1}, {1, 7}, {7, 7}, {7, 8}}, steps[0].Cells)
+		// only the cells of column 7 that see the fin
+		assert.Equal(t, []Candidate{{6, 7, N}, {8, 7, N}}, steps[0].Eliminations)
+		noOpCheck(t, finnedXWing, s)
+	})
+	t.Run("sashimi x-wing", func(t *testing.T) {
+		s := newEmptySudoku()
+		N := arbitraryValue()
+
+		// row 7 has nothing in column 7, just a fin next to it
+		for col := 0; col < 9; col++ {
+			if col != 1 && col != 7 {
+				s.cells[1][col].removeCandidate(N)
+			}
+			if col != 1 && col != 8 {
+				s.cells[7][col].removeCandidate(N)
+			}
+		}
+
+		steps := collect(finnedXWing, s)
+		var elims []Candidate
+		for _, st := range steps {
+			assert.Equal(t, "Sashimi X-Wing", st.Technique)
+			elims = append(elims, st.Eliminations...)
+		}
+		// either way round, 1,7 or 7,8 can be taken as the fin
+		assert.ElementsMatch(t, []Candidate{{6, 7, N}, {8, 7, N}, {0, 8, N}, {2, 8, N}}, elims)
+	})
+	t.Run("fins in more than one box", func(t *testing.T) {
+		s := newEmptySudoku()
+		N := arbitraryValue()
+		for col := 0; col < 9; col++ {
+			if col != 1 && col != 7 {
+				s.cells[1][col].removeCandidate(N)
+			}
+			if col != 1 && col != 4 && col != 7 && col != 8 {
+				s.cells[7][col].removeCandidate(N)
+			}
+		}
+		noOpCheck(t, finnedXWing, s)
+	})
+	t.Run("finned swordfish", func(t *testing.T) {
+		s := newEmptySudoku()
+		N := arbitraryValue()
+
+		// the swordfish from TestSwordfish with a fin at 8,8
+		for col := 0; col < 9; col++ {
+			if col != 0 && col != 3 {
+				s.cells[1][col].removeCandidate(N)
+			}
+			if col != 3 && col != 6 {
+				s.cells[4][col].removeCandidate(N)
+			}
+			if col != 0 && col != 6 && col != 8 {
+				s.cells[7][col].removeCandidate(N)
+			}
+		}
+		noOpCheck(t, swordfish, s)
+
+		steps := collect(finnedSwordfish, s)
+		assert.Equal(t, 1, len(steps))
+		assert.Equal(t, "Finned Swordfish", steps[0].Technique)
+		assert.Equal(t, "rows 1, 4 & 7", steps[0].House)
+		assert.Equal(t, []Candidate{{6, 6, N}, {8, 6, N}}, steps[0].Eliminations)
+	})
+}
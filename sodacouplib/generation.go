@@ -1,64 +1,146 @@
 package sodacouplib
 
 import (
+	"context"
 	"math/rand"
 )
 
 // Generates a problem that is solvable by the heuristic algorithms.
 func GenerateProblem() (*SudokuSquare, error) {
-	sud := randomFilledSudoku()
+	return GenerateProblemWithLayout(ClassicLayout)
+}
+
+// GenerateProblemWithLayout is GenerateProblem for a board of a given shape.
+func GenerateProblemWithLayout(layout Layout) (*SudokuSquare, error) {
+	return GenerateProblemWithVariant(layout, Variant{})
+}
+
+// GenerateProblemWithVariant is GenerateProblemWithLayout with extra rules,
+// such as Sudoku X diagonals.
+func GenerateProblemWithVariant(layout Layout, variant Variant) (*SudokuSquare, error) {
+	return GenerateProblemContext(context.Background(), layout, variant, SolveOptions{})
+}
+
+// GenerateProblemContext is GenerateProblemWithVariant, giving up with a
+// *BudgetError once ctx is done or opts.MaxNodes have been used. The problem
+// made has to be solvable with opts.Strategies, a fixed set of the basic
+// techniques if not set (see generationStrategies); the other options
+// aren't used.
+func GenerateProblemContext(ctx context.Context, layout Layout, variant Variant, opts SolveOptions) (*SudokuSquare, error) {
+	if err := layout.validate(); err != nil {
+		return nil, err
+	}
+	if err := variant.validate(layout); err != nil {
+		return nil, err
+	}
+	set := opts.Strategies
+	if set == nil {
+		set = generationStrategies()
+	}
+	b := newBudget(ctx, opts.MaxNodes)
+	sud, err := randomFilledSudoku(newGeometry(layout, variant), b)
+	if err != nil {
+		return nil, err
+	}
 	// removing is more efficient than adding because of the way backtracking
 	// works.
-	err := removeCellsWhileSolvable(sud)
+	err = removeCellsWhileSolvable(sud, set, b)
 	return sud, err
 }
 
-func randomFilledSudoku() *SudokuSquare {
-	s := newEmptySudoku()
-	for row := 0; row < 9; row++ {
-		for col := 0; col < 9; col++ {
-			if e := s.setCell(row, col, findValueThatFitsCell(s, row, col)); e != nil {
+// Filling cell by cell needs a search at every cell to check the grid can
+// still be finished, which past this size can run for hours. Variants can't
+// take the shortcut though, shuffling doesn't keep their extra rules.
+const maxSearchFillSize = 16
+
+// Errors only if b runs out.
+func randomFilledSudoku(geo *geometry, b *budget) (*SudokuSquare, error) {
+	if geo.size > maxSearchFillSize && geo.isPlain() {
+		return shuffledFilledSudoku(geo), nil
+	}
+	s := newEmptySudokuWithGeometry(geo)
+	for row := 0; row < s.size(); row++ {
+		for col := 0; col < s.size(); col++ {
+			val, err := findValueThatFitsCell(s, row, col, b)
+			if err != nil {
+				return nil, err
+			}
+			if e := s.setCell(row, col, val); e != nil {
 				panic(e)
 			} else if _, e := sanityCheck(s); e != nil {
 				panic(e)
 			}
 		}
 	}
-	return s
+	return s, nil
 }
 
-func findValueThatFitsCell(s *SudokuSquare, row, col int) int {
+func findValueThatFitsCell(s *SudokuSquare, row, col int, b *budget) (int, error) {
 	for {
-		val := rand.Intn(9) + 1
+		val := rand.Intn(s.size()) + 1
 		cell := &s.cells[row][col]
 		if cell.hasCandidate(val) {
-			tmp := *s
+			tmp := s.clone()
 			if e := tmp.setCell(row, col, val); e != nil {
 				panic(e)
 			}
-			_, e := backTrack(&tmp)
-			isSolvable := e == nil
-			if isSolvable {
-				return val
+			count, err := countSolutionsWithin(tmp, 1, b)
+			if err != nil {
+				return 0, err
+			}
+			if count > 0 {
+				return val, nil
+			}
+		}
+	}
+}
+
+// Fills the square with a grid that's valid by construction, each row being
+// the one above shifted along, then shuffles the values, rows within bands,
+// bands, columns within stacks and stacks, all of which keep it valid.
+func shuffledFilledSudoku(geo *geometry) *SudokuSquare {
+	n, br, bc := geo.size, geo.layout.BoxRows, geo.layout.BoxCols
+	values := rand.Perm(n)
+	rows := shuffledLines(n, br)
+	cols := shuffledLines(n, bc)
+	s := newEmptySudokuWithGeometry(geo)
+	for row := 0; row < n; row++ {
+		for col := 0; col < n; col++ {
+			r, c := rows[row], cols[col]
+			val := values[(bc*(r%br)+r/br+c)%n] + 1
+			if e := s.setCell(row, col, val); e != nil {
+				panic(e)
 			}
 		}
 	}
+	return s
+}
+
+// A random order of n lines, that keeps them in groups of `group` lines.
+func shuffledLines(n, group int) []int {
+	var lines []int
+	for _, g := range rand.Perm(n / group) {
+		for _, i := range rand.Perm(group) {
+			lines = append(lines, g*group+i)
+		}
+	}
+	return lines
 }
 
-func removeCellsWhileSolvable(sud *SudokuSquare) error {
-	var cells [9][9]byte
-	copyFrom(*sud, &cells)
+func removeCellsWhileSolvable(sud *SudokuSquare, set *StrategySet, b *budget) error {
+	cells := copyFrom(sud)
+	n := sud.size()
 	err := redoWhileMakingChanges(func() (bool, error) {
-		row, col := rand.Intn(9), rand.Intn(9)
-		if !isSet(cells[row][col]) {
+		row, col := rand.Intn(n), rand.Intn(n)
+		if !isSet(cells.at(row, col)) {
 			return false, nil
 		}
-		removable, err := canRemove(cells, row, col)
+		removable, err := canRemove(cells, row, col, set, b)
 		if err != nil {
 			return false, err
 		}
 		if removable {
-			cells[row][col] = 0
+			cells.set(row, col, 0)
 			return true, nil
 		}
 		return false, nil
@@ -66,16 +148,46 @@ func removeCellsWhileSolvable(sud *SudokuSquare) error {
 	if err != nil {
 		return err
 	}
-	*sud = *newEmptySudoku()
-	copyTo(cells, sud)
+	// the random picks above give up before trying every cell, so finish
+	// with a sweep to make sure no single clue left can be taken away.
+	for row := 0; row < n; row++ {
+		for col := 0; col < n; col++ {
+			if !isSet(cells.at(row, col)) {
+				continue
+			}
+			removable, err := canRemove(cells, row, col, set, b)
+			if err != nil {
+				return err
+			}
+			if removable {
+				cells.set(row, col, 0)
+			}
+		}
+	}
+	*sud = *cells.toSquare()
 	return nil
 }
 
-func canRemove(cells [9][9]byte, row, col int) (bool, error) {
-	cells[row][col] = 0
-	sud := newEmptySudoku()
-	copyTo(cells, sud)
-	return trySolveWithHeuristics(sud)
+// Whether the square can still be solved with the strategies once the clue
+// at row, col is taken out.
+func canRemove(cells grid, row, col int, set *StrategySet, b *budget) (bool, error) {
+	cells = cells.clone()
+	cells.set(row, col, 0)
+	sud := cells.toSquare()
+	return trySolveWithHeuristics(sud, set, ignoreSteps, b)
+}
+
+// The heuristics a generated problem has to be solvable with by default.
+// It's a fixed set rather than DefaultStrategies: canRemove runs for every
+// clue tried, so each technique added to the registry would slow generation
+// down and change the problems a given seed makes.
+func generationStrategies() *StrategySet {
+	set, err := NewStrategySet("Naked Single", "Hidden Single", "Cage Sum", "Pointing Pair", "Claiming Pair",
+		"Naked Pair", "Hidden Pair", "Naked Triple", "X-Wing", "Swordfish", "Jellyfish")
+	if err != nil {
+		panic(err) // all registered
+	}
+	return set
 }
 
 // Keep doing `fn` as long as it's try and give up after
@@ -1,6 +1,8 @@
 package sodacouplib
 
 import (
+	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
 	"testing"
@@ -29,14 +31,106 @@ func TestSampleGeneration(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestGenerationIgnoresRegistry(t *testing.T) {
+	keepRegistry(t)
+	called := 0
+	err := RegisterStrategy(NewStrategy("Test Expensive", func(*SudokuSquare, StepFunc) (bool, error) {
+		called++
+		return false, nil
+	}))
+	assert.NoError(t, err)
+
+	_, err = GenerateProblemWithLayout(Layout{2, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, called)
+}
+
+func TestGenerationStrategies(t *testing.T) {
+	// canRemove runs these for every clue it tries, so nothing costlier than
+	// the basic fish, such as chains or almost locked sets
+	for _, name := range generationStrategies().Names() {
+		assert.LessOrEqual(t, techniqueWeight(name), techniqueWeight("Jellyfish"), name)
+	}
+}
+
 func TestSolvable(t *testing.T) {
 	s, e := GenerateProblem()
 	if e != nil {
 		t.Fatal("failed to generate:", e)
 	}
-	e = s.Solve()
+	_, e = s.Solve(SolveOptions{})
 	if e != nil {
 		t.Fatal("failed to solve:", e)
 	}
 	assert.Equal(t, true, isSolved(s))
 }
+
+func TestNoClueLeftToRemove(t *testing.T) {
+	s, e := GenerateProblem()
+	if e != nil {
+		t.Fatal("failed to generate:", e)
+	}
+	cells := copyFrom(s)
+	for row := 0; row < 9; row++ {
+		for col := 0; col < 9; col++ {
+			if isSet(cells.at(row, col)) {
+				removable, err := canRemove(cells, row, col, generationStrategies(), nil)
+				assert.NoError(t, err)
+				assert.Equal(t, false, removable, "clue at %d,%d could still go", row, col)
+			}
+		}
+	}
+}
+
+func TestGenerationOtherSizes(t *testing.T) {
+	for _, layout := range []Layout{{2, 2}, {2, 3}, {3, 2}} {
+		s, e := GenerateProblemWithLayout(layout)
+		if e != nil {
+			t.Fatal("failed to generate:", e)
+		}
+		assert.Equal(t, layout.Size(), s.size())
+		unique, e := IsUnique(s)
+		assert.NoError(t, e)
+		assert.Equal(t, true, unique)
+		_, e = s.Solve(SolveOptions{})
+		if e != nil {
+			t.Fatal("failed to solve:", e)
+		}
+		assert.Equal(t, true, isSolved(s))
+	}
+}
+
+func TestShuffledFilledSudoku(t *testing.T) {
+	s, e := randomFilledSudoku(newGeometry(Layout{5, 5}, Variant{}), nil)
+	assert.NoError(t, e)
+	assert.Equal(t, true, isSolved(s))
+	_, e = sanityCheck(s)
+	assert.NoError(t, e)
+}
+
+func TestGenerateProblemContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := GenerateProblemContext(ctx, ClassicLayout, Variant{}, SolveOptions{})
+	var spent *BudgetError
+	assert.Equal(t, true, errors.As(err, &spent))
+	assert.Equal(t, true, errors.Is(err, context.Canceled))
+
+	_, err = GenerateProblemContext(context.Background(), ClassicLayout, Variant{}, SolveOptions{MaxNodes: 50})
+	assert.Equal(t, true, errors.As(err, &spent))
+	assert.Equal(t, true, errors.Is(err, ErrNodeLimit))
+	assert.Equal(t, 50, spent.Nodes)
+
+	s, err := GenerateProblemContext(context.Background(), ClassicLayout, Variant{}, SolveOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, true, s.SetCount() < 81)
+
+	t.Run("strategies", func(t *testing.T) {
+		singles, _ := NewStrategySet("Naked Single")
+		s, err := GenerateProblemContext(context.Background(), Layout{2, 2}, Variant{}, SolveOptions{Strategies: singles})
+		assert.NoError(t, err)
+		solved, err := trySolveWithHeuristics(s, singles, ignoreSteps, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, true, solved)
+	})
+}
@@ -0,0 +1,187 @@
+package sodacouplib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Difficulty is the tier a puzzle falls into, going by the hardest technique
+// needed to solve it.
+type Difficulty int
+
+const (
+	Easy Difficulty = iota
+	Medium
+	Hard
+	Expert
+)
+
+func (d Difficulty) String() string {
+	switch d {
+	case Easy:
+		return "easy"
+	case Medium:
+		return "medium"
+	case Hard:
+		return "hard"
+	default:
+		return "expert"
+	}
+}
+
+// How much one use of each technique adds to a puzzle's score. The hardest
+// technique used also decides the tier, see difficultyOf. Strategies are
+// looked up by name here too, to try the simplest first when grading.
+var techniqueWeights = map[string]int{
+	"Naked Single":            1,
+	"Hidden Single":           1,
+	"Cage Sum":                2,
+	"Pointing Pair":           3,
+	"Claiming Pair":           3,
+	"Naked Pair":              4,
+	"Hidden Pair":             5,
+	"Naked Triple":            6,
+	"Hidden Triple":           7,
+	"Naked Quad":              8,
+	"Hidden Quad":             9,
+	"Skyscraper":              7,
+	"2-String Kite":           7,
+	"Turbot Fish":             8,
+	"Empty Rectangle":         8,
+	"X-Wing":                  8,
+	"Swordfish":               10,
+	"Jellyfish":               12,
+	"XY-Wing":                 9,
+	"XYZ-Wing":                10,
+	"Finned X-Wing":           10,
+	"Sashimi X-Wing":          10,
+	"Finned Swordfish":        12,
+	"Sashimi Swordfish":       12,
+	"Finned Jellyfish":        14,
+	"Sashimi Jellyfish":       14,
+	"Simple Coloring":         12, // finds Color Traps and Color Wraps
+	"Color Trap":              12,
+	"Color Wrap":              12,
+	"Sue de Coq":              14,
+	"X-Cycle":                 15,
+	"3D Medusa":               15,
+	"Unique Rectangle Type 1": 8,
+	"Unique Rectangle Type 2": 9,
+	"Unique Rectangle Type 3": 10,
+	"Unique Rectangle Type 4": 9,
+	"Hidden Unique Rectangle": 10,
+	"BUG+1":                   7,
+	"ALS-XZ":                  16,
+	"ALS-XY-Wing":             18,
+	"Death Blossom":           20,
+	"AIC":                     18,
+	"Discontinuous Nice Loop": 18,
+	"Cell Forcing Chain":      25,
+	"House Forcing Chain":     25,
+	"Backtracking":            100,
+}
+
+// Techniques we don't know about (registered by callers) are assumed to be
+// on the hard side.
+const unknownTechniqueWeight = 10
+
+func techniqueWeight(technique string) int {
+	if w, ok := techniqueWeights[technique]; ok {
+		return w
+	}
+	return unknownTechniqueWeight
+}
+
+func difficultyOf(weight int) Difficulty {
+	switch {
+	case weight <= 1:
+		return Easy
+	case weight <= 5:
+		return Medium
+	case weight <= 20:
+		return Hard
+	default:
+		return Expert
+	}
+}
+
+// Grading is how hard a puzzle is for a person to solve.
+type Grading struct {
+	Difficulty Difficulty
+	// Score adds up the weight of every step needed, so harder techniques and
+	// needing them more often both push it up.
+	Score int
+	// Hardest is the name of the hardest technique needed.
+	Hardest string
+	// Techniques counts how many times each technique was needed.
+	Techniques map[string]int
+	// Backtracked is set when the strategies ran out and the rest had to be
+	// guessed.
+	Backtracked bool
+}
+
+// Grade works out how hard a puzzle is by solving a copy of it one step at a
+// time, always taking a deduction from the simplest strategy that has one.
+// The strategies are those enabled in DefaultStrategies, so the ones that
+// assume a unique solution are never used: a puzzle they'd help with grades
+// as needing something harder instead.
+func Grade(sud *SudokuSquare) (Grading, error) {
+	g := Grading{Techniques: make(map[string]int)}
+	opts := SolveOptions{Strategies: gradingStrategies()}
+	s := sud.clone()
+	for !isSolved(s) {
+		st, err := s.NextStep(opts)
+		if err != nil {
+			return g, err
+		}
+		if st == nil {
+			if _, err := fastBackTrack(s, nil); err != nil {
+				return g, err
+			}
+			g.Backtracked = true
+			g.add("Backtracking")
+			break
+		}
+		g.add(st.Technique)
+	}
+	return g, nil
+}
+
+// The enabled default strategies ordered by techniqueWeight, keeping the
+// registry's order between those of the same weight.
+func gradingStrategies() *StrategySet {
+	strategies := DefaultStrategies().Enabled()
+	sort.SliceStable(strategies, func(i, j int) bool {
+		return techniqueWeight(strategies[i].Name()) < techniqueWeight(strategies[j].Name())
+	})
+	names := make([]string, len(strategies))
+	for i, s := range strategies {
+		names[i] = s.Name()
+	}
+	set, err := NewStrategySet(names...)
+	if err != nil {
+		panic(err) // names just came from the registry
+	}
+	return set
+}
+
+func (g *Grading) add(technique string) {
+	w := techniqueWeight(technique)
+	g.Score += w
+	g.Techniques[technique]++
+	if g.Hardest == "" || w > techniqueWeight(g.Hardest) {
+		g.Hardest = technique
+		g.Difficulty = difficultyOf(w)
+	}
+}
+
+func (g Grading) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (score %d", g.Difficulty, g.Score)
+	if g.Hardest != "" {
+		fmt.Fprintf(&sb, ", hardest technique %s", g.Hardest)
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
@@ -0,0 +1,112 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGrade(t *testing.T) {
+	sampleProblems := []struct {
+		name, problem string
+		difficulty    Difficulty
+		hardest       string
+		backtracked   bool
+	}{{
+		"only singles",
+		`
+		__8 7_4 ___
+		45_ 82_ _36
+		2_3 6__ 9__
+
+		_12 _87 ___
+		_9_ 2_3 _5_
+		___ 14_ 89_
+
+		__7 __6 3_4
+		64_ _78 _21
+		___ 4_2 6__
+		`, Easy, "Naked Single", false,
+	}, {
+		"needs pointing pairs",
+		`
+		___ __9 ___
+		_9_ ___ _65
+		8__ 3__ ___
+
+		__3 ___ __6
+		___ 7__ 82_
+		__1 ___ 34_
+
+		__5 8__ ___
+		___ _37 ___
+		62_ 1__ __9
+		`, Medium, "Pointing Pair", false,
+	}, {
+		"needs backtracking",
+		`
+		8__ ___ ___
+		__3 6__ ___
+		_7_ _9_ 2__
+
+		_5_ __7 ___
+		___ _45 7__
+		___ 1__ _3_
+
+		__1 ___ _68
+		__8 5__ _1_
+		_9_ ___ 4__
+		`, Expert, "Backtracking", true,
+	}}
+	for _, tc := range sampleProblems {
+		tc := tc // for parallel
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewSudokuSquare(tc.problem)
+			if err != nil {
+				t.Fatal("got unexpected error from valid input:", err)
+			}
+			clues := s.SetCount()
+
+			g, err := Grade(s)
+			if err != nil {
+				t.Fatal("got unexpected error from grading:", err)
+			}
+
+			assert.Equal(t, tc.difficulty, g.Difficulty)
+			assert.Equal(t, tc.hardest, g.Hardest)
+			assert.Equal(t, tc.backtracked, g.Backtracked)
+			assert.Equal(t, clues, s.SetCount(), "grading should leave the problem alone")
+		})
+	}
+}
+
+func TestGradeScore(t *testing.T) {
+	g := Grading{Techniques: make(map[string]int)}
+	g.add("Naked Single")
+	g.add("Naked Single")
+	g.add("X-Wing")
+	g.add("Hidden Single")
+
+	assert.Equal(t, 1+1+8+1, g.Score)
+	assert.Equal(t, Hard, g.Difficulty)
+	assert.Equal(t, "X-Wing", g.Hardest)
+	assert.Equal(t, 2, g.Techniques["Naked Single"])
+	assert.Equal(t, "hard (score 11, hardest technique X-Wing)", g.String())
+}
+
+func TestGradingStrategies(t *testing.T) {
+	names := gradingStrategies().Names()
+	for i := 1; i < len(names); i++ {
+		assert.LessOrEqual(t, techniqueWeight(names[i-1]), techniqueWeight(names[i]), "%s before %s", names[i-1], names[i])
+	}
+	index := func(name string) int {
+		for i, n := range names {
+			if n == name {
+				return i
+			}
+		}
+		return -1
+	}
+	assert.Less(t, index("Simple Coloring"), index("Sue de Coq"))
+	assert.Equal(t, -1, index("Unique Rectangle"))
+}
@@ -1,36 +1,43 @@
 package sodacouplib
 
 import (
-	"log"
+	"fmt"
 	"math"
-	"math/bits"
 )
 
-type sudokuAlgo func(*SudokuSquare) (bool, error)
+// A sudokuAlgo makes what changes it can to the square, handing a Step to
+// `step` for each deduction, and reports whether anything changed. It stops
+// straight after any deduction `step` says no to (see report).
+type sudokuAlgo func(sud *SudokuSquare, step StepFunc) (bool, error)
 
-func nakedSingle(sud *SudokuSquare) (bool, error) {
+func nakedSingle(sud *SudokuSquare, step StepFunc) (bool, error) {
 	return applyToCells(sud, func(cell *SudokuCell) (bool, error) {
 		changes := false
 
-		// candidates is a mask between 2^1 -> 2^9
+		// candidates is a mask between 2^1 -> 2^size
 		hasOneAvailableValue := (cell.candidates & (cell.candidates - 1)) == 0
 		if !cell.isSet && hasOneAvailableValue {
 			value := int(math.Log2(float64(cell.candidates)))
-			if e := sud.setCell(cell.row, cell.col, value); e != nil {
+			st := Step{Technique: "Naked Single"}
+			st.addCell(cell)
+			if e := st.place(sud, cell, value); e != nil {
 				return false, e
 			}
-			log.Printf("Cell %d,%d has single value %d", cell.row, cell.col, value)
+			if e := report(step, st); e != nil {
+				return true, e
+			}
 			changes = true
 		}
 		return changes, nil
 	})
 }
 
-// where a value is only fits into 1 of the 9 cells of a row/col/block
-func hiddenSingle(sud *SudokuSquare) (bool, error) {
+// where a value is only fits into 1 of the cells of a row/col/block
+func hiddenSingle(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
 	return applyToNonagons(sud, func(nona nonagon) (bool, error) {
 		changes := false
-		for val := 1; val <= 9; val++ {
+		for val := 1; val <= n; val++ {
 			availablePlaces := 0
 			idx := -1
 			for i, cell := range nona.cells {
@@ -44,55 +51,65 @@ func hiddenSingle(sud *SudokuSquare) (bool, error) {
 			}
 			if availablePlaces == 1 {
 				cell := nona.cells[idx]
-				if e := sud.setCell(cell.row, cell.col, val); e != nil {
+				st := Step{Technique: "Hidden Single", House: nona.name}
+				st.addCell(cell)
+				if e := st.place(sud, cell, val); e != nil {
 					return false, e
 				}
+				if e := report(step, st); e != nil {
+					return true, e
+				}
 				changes = true
-				log.Printf("%s has hidden single at %d,%d for value %d",
-					nona.name, cell.row, cell.col, val)
 			}
 		}
 		return changes, nil
 	})
 }
 
-// if a candidate has only 2 or 3 available cells in a block that are along a line, then
+// if a candidate has only 2 or more available cells in a block that are along a line, then
 // that's a pointing pair that removes that candiate as an option along that line outside
-// the block
-func pointingPair(sud *SudokuSquare) (bool, error) {
-	return applyToBlocks(
-		sud,
-		func(r1, r2, c1, c2 int) (bool, error) {
-			return pointingPairBlockFn(sud, r1, r2, c1, c2)
-		})
+// the block. Blocks here are the boxes, or the regions of a jigsaw sudoku.
+func pointingPair(sud *SudokuSquare, step StepFunc) (bool, error) {
+	changes := false
+	for b := range sud.geo.blocks {
+		impacting, err := pointingPairBlockFn(sud, step, b)
+		changes = changes || impacting
+		if err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
 }
 
-// for each value, check each block row or column to see if it has a pointing pair for that value
-func pointingPairBlockFn(sud *SudokuSquare, blkRowStart, blkRowEnd, blkColStart, blkColEnd int) (bool, error) {
+// for each value, check the block to see if it has a pointing pair for that value
+func pointingPairBlockFn(sud *SudokuSquare, step StepFunc, b int) (bool, error) {
+	n := sud.size()
+	geo := sud.geo
+	block := geo.houses[geo.blocks[b]]
 	changes := false
-	for val := 1; val <= 9; val++ {
+	for val := 1; val <= n; val++ {
 		alignedPlaces := 0
 		pointingPairRow := -1
 		pointingPairCol := -1
-
-		for row := blkRowStart; row <= blkRowEnd; row++ {
-			for col := blkColStart; col <= blkColEnd; col++ {
-
-				cell := sud.cells[row][col]
-				if cell.hasCandidate(val) {
-					alignedPlaces++
-					if pointingPairRow == -1 {
-						pointingPairRow = row
-					} else if pointingPairRow != row {
-						// fail, not same row
-						pointingPairRow = -2
-					}
-					if pointingPairCol == -1 {
-						pointingPairCol = col
-					} else if pointingPairCol != col {
-						// fail, not same col
-						pointingPairCol = -2
-					}
+		st := Step{Technique: "Pointing Pair", House: block.name}
+
+		for _, idx := range block.cells {
+			row, col := idx/n, idx%n
+			cell := &sud.cells[row][col]
+			if cell.hasCandidate(val) {
+				st.addCell(cell)
+				alignedPlaces++
+				if pointingPairRow == -1 {
+					pointingPairRow = row
+				} else if pointingPairRow != row {
+					// fail, not same row
+					pointingPairRow = -2
+				}
+				if pointingPairCol == -1 {
+					pointingPairCol = col
+				} else if pointingPairCol != col {
+					// fail, not same col
+					pointingPairCol = -2
 				}
 			}
 		}
@@ -100,17 +117,15 @@ func pointingPairBlockFn(sud *SudokuSquare, blkRowStart, blkRowEnd, blkColStart,
 		hasHorizontalPointingPair := pointingPairRow >= 0 && alignedPlaces > 1
 		if hasHorizontalPointingPair {
 			impacting := false
-			for col := 0; col < 9; col++ {
-				if col < blkColStart || col > blkColEnd {
-					cell := &sud.cells[pointingPairRow][col]
-					if cell.hasCandidate(val) {
-						cell.removeCandidate(val)
-						impacting = true
-					}
+			for col := 0; col < n; col++ {
+				if geo.cellBlock[pointingPairRow*n+col] != b {
+					impacting = st.eliminate(&sud.cells[pointingPairRow][col], val) || impacting
 				}
 			}
 			if impacting {
-				log.Printf("  row  %d pointing pair for value %d", pointingPairRow, val)
+				if e := report(step, st); e != nil {
+					return true, e
+				}
 			}
 			changes = changes || impacting
 		}
@@ -118,17 +133,15 @@ func pointingPairBlockFn(sud *SudokuSquare, blkRowStart, blkRowEnd, blkColStart,
 		hasVerticalPointingPair := pointingPairCol >= 0 && alignedPlaces > 1
 		if hasVerticalPointingPair {
 			impacting := false
-			for row := 0; row < 9; row++ {
-				if row < blkRowStart || row > blkRowEnd {
-					cell := &sud.cells[row][pointingPairCol]
-					if cell.hasCandidate(val) {
-						cell.removeCandidate(val)
-						impacting = true
-					}
+			for row := 0; row < n; row++ {
+				if geo.cellBlock[row*n+pointingPairCol] != b {
+					impacting = st.eliminate(&sud.cells[row][pointingPairCol], val) || impacting
 				}
 			}
 			if impacting {
-				log.Printf("column %d pointing pair for value %d", pointingPairCol, val)
+				if e := report(step, st); e != nil {
+					return true, e
+				}
 			}
 			changes = changes || impacting
 		}
@@ -142,251 +155,79 @@ func pointingPairBlockFn(sud *SudokuSquare, blkRowStart, blkRowEnd, blkColStart,
 // of that block.
 // Is called a 'claimingPair' to distinguish it from a hiddenSingle but the algorithm
 // below doesn't care if it's a single/pair/triple.
-func claimingPair(sud *SudokuSquare) (bool, error) {
+func claimingPair(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
+	geo := sud.geo
 	changes := false
-	for val := 1; val <= 9; val++ {
+	for val := 1; val <= n; val++ {
 		// for each block
-		for blockStartRow := 0; blockStartRow < 9; blockStartRow += 3 {
-			for blockStartCol := 0; blockStartCol < 9; blockStartCol += 3 {
-				blockEndRow, blockEndCol := blockStartRow+2, blockStartCol+2
-
-				// horizontal
-				for testRow := blockStartRow; testRow <= blockEndRow; testRow++ {
-
-					availableOnRowInsideBlock := false
-					for col := blockStartCol; col <= blockEndCol; col++ {
-						cell := sud.cells[testRow][col]
-						if cell.hasCandidate(val) {
+		for b, h := range geo.blocks {
+			block := geo.houses[h]
+			rows, cols := linesThrough(block, n)
+
+			// horizontal
+			for _, testRow := range rows {
+				st := Step{Technique: "Claiming Pair", House: fmt.Sprintf("row %d", testRow)}
+
+				availableOnRowInsideBlock := false
+				availableOnRowOutsideBlock := false
+				for col := 0; col < n; col++ {
+					cell := &sud.cells[testRow][col]
+					if cell.hasCandidate(val) {
+						if geo.cellBlock[testRow*n+col] == b {
+							st.addCell(cell)
 							availableOnRowInsideBlock = true
+						} else {
+							availableOnRowOutsideBlock = true
 						}
 					}
-					availableOnRowOutsideBlock := false
-					for col := 0; col < 9; col++ {
-						if col < blockStartCol || col > blockEndCol {
-							cell := sud.cells[testRow][col]
-							if cell.hasCandidate(val) {
-								availableOnRowOutsideBlock = true
-							}
-						}
-					}
-					if availableOnRowInsideBlock && !availableOnRowOutsideBlock {
-						impacting := false
-						for row := blockStartRow; row <= blockEndRow; row++ {
-							if row != testRow {
-								for col := blockStartCol; col <= blockEndCol; col++ {
-									cell := &sud.cells[row][col]
-									if cell.hasCandidate(val) {
-										cell.removeCandidate(val)
-										impacting = true
-									}
-								}
-							}
-						}
-						if impacting {
-							log.Printf("  row  %d claiming pair for value %d", testRow, val)
-						}
-						changes = changes || impacting
-					}
 				}
-
-				// vertical
-				for testCol := blockStartCol; testCol <= blockEndCol; testCol++ {
-					availableOnColInsideBlock := false
-					for row := blockStartRow; row <= blockEndRow; row++ {
-						cell := sud.cells[row][testCol]
-						if cell.hasCandidate(val) {
-							availableOnColInsideBlock = true
-						}
-					}
-					availableOnColOutsideBlock := false
-					for row := 0; row < 9; row++ {
-						if row < blockStartRow || row > blockEndRow {
-							cell := sud.cells[row][testCol]
-							if cell.hasCandidate(val) {
-								availableOnColOutsideBlock = true
-							}
+				if availableOnRowInsideBlock && !availableOnRowOutsideBlock {
+					impacting := false
+					for _, idx := range block.cells {
+						if idx/n != testRow {
+							impacting = st.eliminate(&sud.cells[idx/n][idx%n], val) || impacting
 						}
 					}
-					if availableOnColInsideBlock && !availableOnColOutsideBlock {
-						impacting := false
-						for col := blockStartCol; col <= blockEndCol; col++ {
-							if col != testCol {
-								for row := blockStartRow; row <= blockEndRow; row++ {
-									cell := &sud.cells[row][col]
-									if cell.hasCandidate(val) {
-										cell.removeCandidate(val)
-										impacting = true
-									}
-								}
-							}
-						}
-						if impacting {
-							log.Printf("column %d claiming pair for value %d", testCol, val)
+					if impacting {
+						if e := report(step, st); e != nil {
+							return true, e
 						}
-						changes = changes || impacting
 					}
+					changes = changes || impacting
 				}
 			}
-		}
-	}
-	return changes, nil
-}
-
-// if two values are unique to two cells already then they cannot go in elsewhere
-func nakedPair(sud *SudokuSquare) (bool, error) {
-	return applyToNonagons(sud, func(nona nonagon) (bool, error) {
-		changes := false
-		mc := make(map[uint16]int)
-
-		for _, cell := range nona.cells {
-			if !cell.isSet {
-				mc[cell.candidates]++
-			}
-		}
-
-		for mask, count := range mc {
-			hasNakedPair := count == 2 && bits.OnesCount16(mask) == 2
-			if hasNakedPair {
-				impacting := false
-				for _, cell := range nona.cells {
-					if !cell.isSet && cell.candidates&mask > 0 && cell.candidates != mask {
-						cell.candidates = cell.candidates &^ mask
-						impacting = true
-					}
-				}
-				if impacting {
-					log.Printf("%s naked pair for candidates %s", nona.name, maskToString(mask))
-				}
-				changes = changes || impacting
-			}
-		}
 
-		return changes, nil
-	})
-}
+			// vertical
+			for _, testCol := range cols {
+				st := Step{Technique: "Claiming Pair", House: fmt.Sprintf("column %d", testCol)}
 
-// if two values can only go into the same two cells then
-// remove other values from those two cells
-func hiddenPair(sud *SudokuSquare) (bool, error) {
-	return applyToNonagons(sud, func(nona nonagon) (bool, error) {
-		changes := false
-		for v1 := 1; v1 <= 9; v1++ {
-			for v2 := 1; v2 < v1; v2++ {
-				mismatch := false
-				matchCnt := 0
-				for _, cell := range nona.cells {
-					b1 := cell.hasCandidate(v1)
-					b2 := cell.hasCandidate(v2)
-					if b1 && b2 {
-						matchCnt++
-					} else if b1 || b2 {
-						mismatch = true
-						break
+				availableOnColInsideBlock := false
+				availableOnColOutsideBlock := false
+				for row := 0; row < n; row++ {
+					cell := &sud.cells[row][testCol]
+					if cell.hasCandidate(val) {
+						if geo.cellBlock[row*n+testCol] == b {
+							st.addCell(cell)
+							availableOnColInsideBlock = true
+						} else {
+							availableOnColOutsideBlock = true
+						}
 					}
 				}
-				if !mismatch && matchCnt == 2 {
+				if availableOnColInsideBlock && !availableOnColOutsideBlock {
 					impacting := false
-					for idx := 0; idx < 9; idx++ {
-						cell := nona.cells[idx]
-						if cell.hasCandidate(v1) && cell.hasCandidate(v2) {
-							for v3 := 1; v3 <= 9; v3++ {
-								if v3 != v1 && v3 != v2 {
-									if cell.hasCandidate(v3) {
-										cell.removeCandidate(v3)
-										impacting = true
-									}
-								}
-							}
+					for _, idx := range block.cells {
+						if idx%n != testCol {
+							impacting = st.eliminate(&sud.cells[idx/n][idx%n], val) || impacting
 						}
 					}
 					if impacting {
-						log.Printf("%s hidden pair for values %d&%d", nona.name, v2, v1)
-					}
-					changes = changes || impacting
-				}
-			}
-		}
-		return changes, nil
-	})
-}
-
-// if two rows have identical two remaining cells for a candidate then
-// they form a square that removes that candidate from the perpendicular columns
-// (and vice/versa for columns).
-func xWing(sud *SudokuSquare) (bool, error) {
-	changes := false
-	// row based
-	for r1 := 0; r1 < 9; r1++ {
-		for r2 := r1 + 1; r2 < 9; r2++ {
-			for val := 1; val <= 9; val++ {
-				impacting := false
-				matchCount := 0
-				for col := 0; col < 9 && matchCount <= 2; col++ {
-					b1 := sud.cells[r1][col].hasCandidate(val)
-					b2 := sud.cells[r2][col].hasCandidate(val)
-					if b1 && b2 {
-						matchCount++
-					} else if b1 || b2 {
-						matchCount = 0
-						break
-					}
-				}
-				xWingFound := matchCount == 2
-				if xWingFound {
-					for col := 0; col < 9; col++ {
-						if sud.cells[r1][col].hasCandidate(val) && sud.cells[r2][col].hasCandidate(val) {
-							for r := 0; r < 9; r++ {
-								if r != r1 && r != r2 {
-									if sud.cells[r][col].hasCandidate(val) {
-										sud.cells[r][col].removeCandidate(val)
-										impacting = true
-									}
-								}
-							}
-						}
-					}
-				}
-				if impacting {
-					log.Printf("row %d and row %d have xwing for value %d", r1, r2, val)
-					changes = true
-				}
-			}
-		}
-	}
-	// column based
-	for c1 := 0; c1 < 9; c1++ {
-		for c2 := c1 + 1; c2 < 9; c2++ {
-			for val := 1; val <= 9; val++ {
-				impacting := false
-				matchCount := 0
-				for row := 0; row < 9 && matchCount <= 2; row++ {
-					b1 := sud.cells[row][c1].hasCandidate(val)
-					b2 := sud.cells[row][c2].hasCandidate(val)
-					if b1 && b2 {
-						matchCount++
-					} else if b1 || b2 {
-						matchCount = 0
-						break
-					}
-				}
-				xWingFound := matchCount == 2
-				if xWingFound {
-					for row := 0; row < 9 && matchCount <= 2; row++ {
-						if sud.cells[row][c1].hasCandidate(val) && sud.cells[row][c2].hasCandidate(val) {
-							for c := 0; c < 9; c++ {
-								if c != c1 && c != c2 {
-									if sud.cells[row][c].hasCandidate(val) {
-										sud.cells[row][c].removeCandidate(val)
-										impacting = true
-									}
-								}
-							}
+						if e := report(step, st); e != nil {
+							return true, e
 						}
 					}
-				}
-				if impacting {
-					log.Printf("col %d and col %d have xwing for value %d", c1, c2, val)
-					changes = true
+					changes = changes || impacting
 				}
 			}
 		}
@@ -394,47 +235,21 @@ func xWing(sud *SudokuSquare) (bool, error) {
 	return changes, nil
 }
 
-// if two values are unique to two cells already then they cannot go in elsewhere
-func nakedTriple(sud *SudokuSquare) (bool, error) {
-	return applyToNonagons(sud, func(nona nonagon) (bool, error) {
-		changes := false
-		mc := make(map[uint16]int)
-
-		for _, cell := range nona.cells {
-			if !cell.isSet {
-				numCans := bits.OnesCount16(cell.candidates)
-				if numCans == 3 {
-					mc[cell.candidates]++
-				} else if numCans == 2 {
-					for n := 1; n <= 9; n++ {
-						msk := uint16(1 << n)
-						if msk&cell.candidates == 0 {
-							mc[msk|cell.candidates]++
-						}
-					}
-				}
-			}
+// The rows and columns a block has cells in, in order.
+func linesThrough(block house, n int) ([]int, []int) {
+	inRow, inCol := make([]bool, n), make([]bool, n)
+	for _, idx := range block.cells {
+		inRow[idx/n] = true
+		inCol[idx%n] = true
+	}
+	var rows, cols []int
+	for i := 0; i < n; i++ {
+		if inRow[i] {
+			rows = append(rows, i)
 		}
-
-		for mask, count := range mc {
-			hasNakedTriple := count == 3 && bits.OnesCount16(mask) == 3
-			if hasNakedTriple {
-				impacting := false
-				for _, cell := range nona.cells {
-					notTripletCell := cell.candidates&^mask > 0
-					hasTripletCandidates := cell.candidates&mask > 0
-					if !cell.isSet && notTripletCell && hasTripletCandidates {
-						cell.candidates = cell.candidates &^ mask
-						impacting = true
-					}
-				}
-				if impacting {
-					log.Printf("%s naked triple for candidates %s", nona.name, maskToString(mask))
-				}
-				changes = changes || impacting
-			}
+		if inCol[i] {
+			cols = append(cols, i)
 		}
-
-		return changes, nil
-	})
+	}
+	return rows, cols
 }
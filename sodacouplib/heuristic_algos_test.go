@@ -873,8 +873,8 @@ func neighbour(n int) int {
 	return (n + 9) % 9
 }
 
-func apply(t *testing.T, fn func(s *SudokuSquare) (bool, error), s *SudokuSquare) {
-	changes, err := fn(s)
+func apply(t *testing.T, fn sudokuAlgo, s *SudokuSquare) {
+	changes, err := fn(s, ignoreSteps)
 	if err != nil {
 		t.Fatal("Got unexpected algorithm error", err)
 	}
@@ -884,8 +884,8 @@ func apply(t *testing.T, fn func(s *SudokuSquare) (bool, error), s *SudokuSquare
 
 }
 
-func noOpCheck(t *testing.T, fn func(s *SudokuSquare) (bool, error), s *SudokuSquare) {
-	changes, err := fn(s)
+func noOpCheck(t *testing.T, fn sudokuAlgo, s *SudokuSquare) {
+	changes, err := fn(s, ignoreSteps)
 	if err != nil {
 		t.Fatal("Got unexpected algorithm error", err)
 	}
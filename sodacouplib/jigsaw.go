@@ -0,0 +1,103 @@
+package sodacouplib
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseRegions reads a jigsaw region map: one character per cell, in rows,
+// with the cells of each region sharing a character. Whitespace is ignored,
+// so a 9×9 map could look like:
+//
+//	AAABBBCCC
+//	AAABBBCCC
+//	ADABBBCFC
+//	DDDEEEFFF
+//	...
+//
+// Regions are numbered in the order their characters first appear.
+func ParseRegions(s string) ([][]int, error) {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+	cells := []rune(s)
+	n := isqrt(len(cells))
+	if n < 1 {
+		return nil, &ParseError{msg: "region map isn't square"}
+	}
+	ids := make(map[rune]int)
+	regions := make([][]int, n)
+	for row := range regions {
+		regions[row] = make([]int, n)
+		for col := range regions[row] {
+			r := cells[row*n+col]
+			if _, ok := ids[r]; !ok {
+				ids[r] = len(ids)
+			}
+			regions[row][col] = ids[r]
+		}
+	}
+	return regions, nil
+}
+
+// Regions have to cover the board with size regions of size cells each, the
+// cells of each one joined up through their sides.
+func validateRegions(regions [][]int, size int) error {
+	if len(regions) != size {
+		return houseError(ErrInvalidVariant, "", 0, "region map has %d rows, expected %d", len(regions), size)
+	}
+	cells := make([][]CellRef, size)
+	for row := range regions {
+		if len(regions[row]) != size {
+			return houseError(ErrInvalidVariant, fmt.Sprintf("row %d", row), 0,
+				"region map row %d has %d cells, expected %d", row, len(regions[row]), size)
+		}
+		for col, r := range regions[row] {
+			if r < 0 || r >= size {
+				return cellError(ErrInvalidVariant, row, col, 0, "",
+					"cell %d,%d is in region %d, expected 0 to %d", row, col, r, size-1)
+			}
+			cells[r] = append(cells[r], CellRef{row, col})
+		}
+	}
+	for r := range cells {
+		if len(cells[r]) != size {
+			return houseError(ErrInvalidVariant, regionName(r), 0, "region %d has %d cells, expected %d", r, len(cells[r]), size)
+		}
+		if !isConnected(regions, r, cells[r][0]) {
+			return houseError(ErrInvalidVariant, regionName(r), 0, "region %d is in more than one piece", r)
+		}
+	}
+	return nil
+}
+
+// Name of region r, as errors and steps refer to it.
+func regionName(r int) string {
+	return fmt.Sprintf("region %d", r)
+}
+
+// Checks every cell of region r can be reached from `start` without leaving
+// the region.
+func isConnected(regions [][]int, r int, start CellRef) bool {
+	n := len(regions)
+	seen := map[CellRef]bool{start: true}
+	todo := []CellRef{start}
+	for len(todo) > 0 {
+		c := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		for _, next := range []CellRef{{c.Row - 1, c.Col}, {c.Row + 1, c.Col}, {c.Row, c.Col - 1}, {c.Row, c.Col + 1}} {
+			if next.Row < 0 || next.Row >= n || next.Col < 0 || next.Col >= n {
+				continue
+			}
+			if regions[next.Row][next.Col] == r && !seen[next] {
+				seen[next] = true
+				todo = append(todo, next)
+			}
+		}
+	}
+	return len(seen) == n
+}
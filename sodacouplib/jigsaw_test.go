@@ -0,0 +1,161 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// The usual boxes with a few cells traded between neighbours.
+const jigsawRegions = `
+	AAABBBCCC
+	AAABBBCCC
+	AADBBBCCC
+	ADDEEFFFF
+	DDDEEEFFF
+	DDDEEEEFF
+	GGGHHHIII
+	GGGHHHIII
+	GGGHHHIII
+`
+
+func TestParseRegions(t *testing.T) {
+	regions, err := ParseRegions("AB BA")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 1}, {1, 0}}, regions)
+
+	regions, err = ParseRegions(jigsawRegions)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 3, 3, 4, 4, 5, 5, 5, 5}, regions[3])
+
+	_, err = ParseRegions("AAB")
+	assert.Error(t, err)
+}
+
+func TestJigsawValidation(t *testing.T) {
+	for name, regions := range map[string]string{
+		"too few regions": `
+			AAAABBBBB
+			AAAABBBBB
+			AAAABBBBB
+			AAAABBBBB
+			AAAABBBBB
+			AAAAABBBB
+			CCCCCCCCC
+			CCCCCCCCC
+			CCCCCCCCC
+		`,
+		"wrong sized region": `
+			AAAABBCCC
+			AAABBBCCC
+			AAABBBCCC
+			DDDEEEFFF
+			DDDEEEFFF
+			DDDEEEFFF
+			GGGHHHIII
+			GGGHHHIII
+			GGGHHHIII
+		`,
+		"region in two pieces": `
+			AAABBBCCC
+			AAABBBCCC
+			ABABBBCCC
+			DDDEEEFFF
+			DDDEEEFFF
+			DDDEEEFFF
+			GGGHHHIII
+			GGGHHHIII
+			GGGHHHIII
+		`,
+		"wrong size for the board": "AB BA",
+	} {
+		r, err := ParseRegions(regions)
+		assert.NoError(t, err, name)
+		_, err = NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Regions: r})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestJigsaw(t *testing.T) {
+	regions, _ := ParseRegions(jigsawRegions)
+	variant := Variant{Regions: regions}
+
+	t.Run("setting a cell rules it out in its region", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, variant)
+		assert.NoError(t, s.setCell(3, 0, 4))
+		// region A reaches down to 3,0 but 4,1 is in region D
+		assert.Equal(t, false, s.cells[0][2].hasCandidate(4))
+		assert.Equal(t, true, s.cells[4][1].hasCandidate(4))
+		assert.Equal(t, false, isValidMove(copyFrom(s), 2, 1, 4))
+		assert.Equal(t, true, isValidMove(copyFrom(s), 4, 1, 4))
+	})
+	t.Run("pointing pair in a region", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, variant)
+		// only 3,1 and 3,2 can hold a 5 inside region D
+		for _, idx := range s.geo.houses[s.geo.blocks[3]].cells {
+			if idx != 3*9+1 && idx != 3*9+2 {
+				s.cells[idx/9][idx%9].removeCandidate(5)
+			}
+		}
+
+		var steps []Step
+		_, err := pointingPair(s, func(st Step) bool {
+			steps = append(steps, st)
+			return true
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(steps))
+		assert.Equal(t, "region 3", steps[0].House)
+		assert.Equal(t, []CellRef{{3, 1}, {3, 2}}, steps[0].Cells)
+		assert.Equal(t, []Candidate{
+			{3, 0, 5}, {3, 3, 5}, {3, 4, 5}, {3, 5, 5}, {3, 6, 5}, {3, 7, 5}, {3, 8, 5},
+		}, steps[0].Eliminations)
+	})
+	t.Run("claiming pair in a region", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, variant)
+		// only 5,5 and 5,6 can hold a 2 in row 5, both in region E
+		for col := 0; col < 9; col++ {
+			if col != 5 && col != 6 {
+				s.cells[5][col].removeCandidate(2)
+			}
+		}
+		apply(t, claimingPair, s)
+		for _, idx := range s.geo.houses[s.geo.blocks[4]].cells {
+			pair := idx == 5*9+5 || idx == 5*9+6
+			assert.Equal(t, pair, s.cells[idx/9][idx%9].hasCandidate(2), "%d,%d", idx/9, idx%9)
+		}
+		// 3,5 is next to region E but in region F
+		assert.Equal(t, true, s.cells[3][5].hasCandidate(2))
+	})
+	t.Run("generate and solve", func(t *testing.T) {
+		s, err := GenerateProblemWithVariant(ClassicLayout, variant)
+		if err != nil {
+			t.Fatal("failed to generate:", err)
+		}
+		unique, err := IsUnique(s)
+		assert.NoError(t, err)
+		assert.Equal(t, true, unique)
+
+		_, err = s.Solve(SolveOptions{})
+		if err != nil {
+			t.Fatal("failed to solve:", err)
+		}
+		seen := make([]map[byte]bool, 9)
+		for r := range seen {
+			seen[r] = map[byte]bool{}
+		}
+		for row := 0; row < 9; row++ {
+			for col := 0; col < 9; col++ {
+				seen[regions[row][col]][s.cells[row][col].value] = true
+			}
+		}
+		for r := range seen {
+			assert.Equal(t, 9, len(seen[r]), "region %d", r)
+		}
+	})
+	t.Run("drawn without boxes", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, variant)
+		assert.Equal(t, " "+strings.Repeat("-", 21)+"\n", strings.SplitAfter(s.String(), "\n")[0])
+		assert.Equal(t, " | _ _ _ _ _ _ _ _ _ |\n", strings.SplitAfter(s.String(), "\n")[1])
+	})
+}
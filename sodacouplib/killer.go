@@ -0,0 +1,270 @@
+package sodacouplib
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cage is a group of cells in a killer sudoku whose values have to add up to
+// Sum, with no value used twice inside the cage.
+type Cage struct {
+	Sum   int
+	Cells []CellRef
+}
+
+// The JSON form of a cage, cells being [row, col] pairs.
+type jsonCage struct {
+	Sum   int      `json:"sum"`
+	Cells [][2]int `json:"cells"`
+}
+
+// ParseCages reads cages either as JSON:
+//
+//	[{"sum": 15, "cells": [[0, 0], [0, 1], [1, 0]]}, ...]
+//
+// or as text, one cage per line with its sum followed by its cells:
+//
+//	15: 0,0 0,1 1,0
+//
+// Blank lines and lines starting with '#' are skipped in the text form.
+func ParseCages(s string) ([]Cage, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") {
+		return parseJSONCages(s)
+	}
+	var cages []Cage
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c, err := parseCageLine(line)
+		if err != nil {
+			return nil, &ParseError{Line: i + 1, msg: err.Error()}
+		}
+		cages = append(cages, c)
+	}
+	return cages, nil
+}
+
+func parseJSONCages(s string) ([]Cage, error) {
+	var raw []jsonCage
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		if syntax, ok := err.(*json.SyntaxError); ok {
+			return nil, parseErrorAt(s, int(syntax.Offset), "%s", err)
+		}
+		return nil, &ParseError{msg: err.Error()}
+	}
+	cages := make([]Cage, len(raw))
+	for i, r := range raw {
+		cages[i].Sum = r.Sum
+		for _, rc := range r.Cells {
+			cages[i].Cells = append(cages[i].Cells, CellRef{rc[0], rc[1]})
+		}
+	}
+	return cages, nil
+}
+
+func parseCageLine(line string) (Cage, error) {
+	var c Cage
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return c, fmt.Errorf("expected \"sum: row,col ...\" but got %q", line)
+	}
+	sum, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return c, fmt.Errorf("bad sum %q", parts[0])
+	}
+	c.Sum = sum
+	for _, field := range strings.Fields(parts[1]) {
+		rc := strings.Split(field, ",")
+		if len(rc) != 2 {
+			return c, fmt.Errorf("bad cell %q", field)
+		}
+		row, err := strconv.Atoi(rc[0])
+		if err != nil {
+			return c, fmt.Errorf("bad cell %q", field)
+		}
+		col, err := strconv.Atoi(rc[1])
+		if err != nil {
+			return c, fmt.Errorf("bad cell %q", field)
+		}
+		c.Cells = append(c.Cells, CellRef{row, col})
+	}
+	return c, nil
+}
+
+// Cages have to be inside the board, not overlap, and have a sum that some
+// set of different values could add up to.
+func validateCages(cages []Cage, size int) error {
+	seen := make(map[CellRef]bool)
+	for i, c := range cages {
+		k := len(c.Cells)
+		if k == 0 || k > size {
+			return houseError(ErrInvalidVariant, cageName(i), 0, "cage %d has %d cells", i, k)
+		}
+		if min, max := k*(k+1)/2, k*(2*size-k+1)/2; c.Sum < min || c.Sum > max {
+			return houseError(ErrInvalidVariant, cageName(i), 0, "cage %d can't add up to %d with %d cells", i, c.Sum, k)
+		}
+		for _, ref := range c.Cells {
+			if ref.Row < 0 || ref.Row >= size || ref.Col < 0 || ref.Col >= size {
+				return cellError(ErrOutOfRange, ref.Row, ref.Col, 0, cageName(i),
+					"cage %d has cell %d,%d outside the board", i, ref.Row, ref.Col)
+			}
+			if seen[ref] {
+				return cellError(ErrInvalidVariant, ref.Row, ref.Col, 0, cageName(i),
+					"cell %d,%d is in more than one cage", ref.Row, ref.Col)
+			}
+			seen[ref] = true
+		}
+	}
+	return nil
+}
+
+// Name of the i'th cage, as errors and steps refer to it.
+func cageName(i int) string {
+	return fmt.Sprintf("cage %d", i)
+}
+
+// The cells of a cage as the geometry keeps them.
+type cage struct {
+	name  string
+	sum   int
+	cells []int // row*size+col of each cell
+}
+
+// Works out which candidates of each unset cell in the cage could still be
+// part of a set of different values adding up to the cage's sum, in the
+// order the unset cells come in the cage. Errors if no such set is left.
+func cageOptions(sud *SudokuSquare, cg cage) ([]*SudokuCell, []uint32, error) {
+	n := sud.size()
+	var used uint32
+	remaining := cg.sum
+	var unset []*SudokuCell
+	for _, idx := range cg.cells {
+		cell := &sud.cells[idx/n][idx%n]
+		if !cell.isSet {
+			unset = append(unset, cell)
+			continue
+		}
+		if used&(1<<cell.value) != 0 {
+			return nil, nil, houseError(ErrContradiction, cg.name, int(cell.value), "%s two values set for %d", cg.name, cell.value)
+		}
+		used |= 1 << cell.value
+		remaining -= int(cell.value)
+	}
+
+	allowed := make([]uint32, len(unset))
+	found := false
+	eachCombination(allCandidates(n)&^used, len(unset), remaining, func(combo uint32) {
+		// every value in the combination needs a cell that can take it and
+		// every cell needs a value from it, otherwise it can't be placed.
+		var covered uint32
+		for _, cell := range unset {
+			if cell.candidates&combo == 0 {
+				return
+			}
+			covered |= cell.candidates & combo
+		}
+		if covered != combo {
+			return
+		}
+		found = true
+		for i, cell := range unset {
+			allowed[i] |= cell.candidates & combo
+		}
+	})
+	if !found {
+		return nil, nil, houseError(ErrContradiction, cg.name, 0, "%s can't add up to %d", cg.name, cg.sum)
+	}
+	return unset, allowed, nil
+}
+
+// Calls fn with the mask of every set of k different values from `avail`
+// that add up to sum.
+func eachCombination(avail uint32, k, sum int, fn func(uint32)) {
+	var recurse func(from, k, sum int, combo uint32)
+	recurse = func(from, k, sum int, combo uint32) {
+		if k == 0 {
+			if sum == 0 {
+				fn(combo)
+			}
+			return
+		}
+		for val := from; val <= maxSize; val++ {
+			// the smallest k values from here on are already too big
+			if k*val+k*(k-1)/2 > sum {
+				return
+			}
+			if avail&(1<<val) != 0 {
+				recurse(val+1, k-1, sum-val, combo|1<<val)
+			}
+		}
+	}
+	recurse(1, k, sum, 0)
+}
+
+// Makes sure no cage has a value twice or can no longer make its sum.
+func checkCages(sud *SudokuSquare) error {
+	for _, cg := range sud.geo.cages {
+		if _, _, err := cageOptions(sud, cg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Removes the candidates that can't be part of any set of values making up
+// a cage's sum.
+func cageSum(sud *SudokuSquare, step StepFunc) (bool, error) {
+	impacting := false
+	for _, cg := range sud.geo.cages {
+		unset, allowed, err := cageOptions(sud, cg)
+		if err != nil {
+			return false, err
+		}
+		st := Step{Technique: "Cage Sum", House: cg.name}
+		for i, cell := range unset {
+			st.addCell(cell)
+			st.eliminateMask(cell, cell.candidates&^allowed[i])
+		}
+		if len(st.Eliminations) > 0 {
+			impacting = true
+			if err := report(step, st); err != nil {
+				return true, err
+			}
+		}
+	}
+	return impacting, nil
+}
+
+// Checks val could go in the cell without repeating a value in its cage or
+// making the cage's sum impossible.
+func cageAllows(cells grid, idx, val int) bool {
+	c := cells.geo.cellCage[idx]
+	if c < 0 {
+		return true
+	}
+	cg := cells.geo.cages[c]
+	sum, empty := val, 0
+	for _, i := range cg.cells {
+		if i == idx {
+			continue
+		}
+		switch v := int(cells.cells[i]); {
+		case v == val:
+			return false
+		case v == 0:
+			empty++
+		default:
+			sum += v
+		}
+	}
+	if empty == 0 {
+		return sum == cg.sum
+	}
+	// the empty cells will add at least 1 + 2 + ... + empty
+	return sum+empty*(empty+1)/2 <= cg.sum
+}
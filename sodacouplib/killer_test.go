@@ -0,0 +1,197 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var emptyClassic = strings.Repeat("_", 81)
+
+// Dominoes along each row and down the last column, with the sums from
+// fullGrid.
+const killerCages = `
+	9: 0,0 0,1
+	14: 0,2 0,3
+	8: 0,4 0,5
+	10: 0,6 0,7
+	11: 1,0 1,1
+	8: 1,2 1,3
+	9: 1,4 1,5
+	15: 1,6 1,7
+	15: 2,0 2,1
+	6: 2,2 2,3
+	10: 2,4 2,5
+	13: 2,6 2,7
+	11: 3,0 3,1
+	11: 3,2 3,3
+	12: 3,4 3,5
+	5: 3,6 3,7
+	12: 4,0 4,1
+	12: 4,2 4,3
+	7: 4,4 4,5
+	11: 4,6 4,7
+	4: 5,0 5,1
+	8: 5,2 5,3
+	13: 5,4 5,5
+	13: 5,6 5,7
+	5: 6,0 6,1
+	15: 6,2 6,3
+	12: 6,4 6,5
+	8: 6,6 6,7
+	9: 7,0 7,1
+	8: 7,2 7,3
+	11: 7,4 7,5
+	9: 7,6 7,7
+	14: 8,0 8,1
+	8: 8,2 8,3
+	8: 8,4 8,5
+	6: 8,6 8,7
+	6: 0,8 1,8
+	7: 2,8 3,8
+	10: 4,8 5,8
+	13: 6,8 7,8
+	9: 8,8
+`
+
+const killerProblem = `
+	1_5 ___ ___
+	7__ ___ _6_
+	___ __3 _5_
+
+	___ 3_7 ___
+	_7_ 8__ ___
+	_1_ ___ __7
+
+	___ ___ 7__
+	___ _2_ 6__
+	__7 _3_ ___
+`
+
+func TestParseCages(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		cages, err := ParseCages(`
+			# top corner
+			10: 0,0 0,1 1,0
+
+			5: 8,8 8,7
+		`)
+		assert.NoError(t, err)
+		assert.Equal(t, []Cage{
+			{10, []CellRef{{0, 0}, {0, 1}, {1, 0}}},
+			{5, []CellRef{{8, 8}, {8, 7}}},
+		}, cages)
+	})
+	t.Run("json", func(t *testing.T) {
+		cages, err := ParseCages(`[
+			{"sum": 10, "cells": [[0, 0], [0, 1], [1, 0]]},
+			{"sum": 5, "cells": [[8, 8], [8, 7]]}
+		]`)
+		assert.NoError(t, err)
+		assert.Equal(t, []Cage{
+			{10, []CellRef{{0, 0}, {0, 1}, {1, 0}}},
+			{5, []CellRef{{8, 8}, {8, 7}}},
+		}, cages)
+	})
+	t.Run("bad input should give error", func(t *testing.T) {
+		for _, bad := range []string{
+			"10 0,0 0,1",
+			"ten: 0,0 0,1",
+			"10: 0,0 0;1",
+			"10: 0,0 0,x",
+			`[{"sum": 10, "cells": [[0, 0]]`,
+		} {
+			_, err := ParseCages(bad)
+			assert.Error(t, err, bad)
+		}
+	})
+}
+
+func TestKillerValidation(t *testing.T) {
+	for name, cages := range map[string][]Cage{
+		"outside the board": {{3, []CellRef{{0, 0}, {0, 9}}}},
+		"overlapping":       {{3, []CellRef{{0, 0}, {0, 1}}}, {4, []CellRef{{0, 1}, {0, 2}}}},
+		"sum too small":     {{2, []CellRef{{0, 0}, {0, 1}}}},
+		"sum too big":       {{18, []CellRef{{0, 0}, {0, 1}}}},
+		"empty":             {{0, nil}},
+	} {
+		_, err := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Cages: cages})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestKillerSolve(t *testing.T) {
+	cages, err := ParseCages(killerCages)
+	if err != nil {
+		t.Fatal("got unexpected error from valid cages:", err)
+	}
+	variant := Variant{Cages: cages}
+
+	t.Run("cages make it unique", func(t *testing.T) {
+		plain, _ := NewSudokuSquare(killerProblem)
+		unique, _ := IsUnique(plain)
+		assert.Equal(t, false, unique)
+
+		s, err := NewSudokuSquareWithVariant(killerProblem, ClassicLayout, variant)
+		assert.NoError(t, err)
+		unique, _ = IsUnique(s)
+		assert.Equal(t, true, unique)
+	})
+	t.Run("heuristics", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(killerProblem, ClassicLayout, variant)
+		steps, err := s.Solve(SolveOptions{})
+		if err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		assertSolution(t, s, fullGrid)
+		techniques := map[string]bool{}
+		for _, st := range steps {
+			techniques[st.Technique] = true
+		}
+		assert.Equal(t, true, techniques["Cage Sum"])
+		assert.Equal(t, false, techniques["Backtracking"])
+	})
+	t.Run("backtracking", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(killerProblem, ClassicLayout, variant)
+		if _, err := backTrack(s, nil); err != nil {
+			t.Fatal("got unexpected error from backtracking:", err)
+		}
+		assertSolution(t, s, fullGrid)
+	})
+}
+
+func TestCageSum(t *testing.T) {
+	t.Run("only combinations that add up are left", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Cages: []Cage{
+			{4, []CellRef{{0, 0}, {0, 1}}},
+		}})
+		apply(t, cageSum, s)
+		assert.Equal(t, "(13)", s.cells[0][0].candidateString())
+		assert.Equal(t, "(13)", s.cells[0][1].candidateString())
+		noOpCheck(t, cageSum, s)
+	})
+	t.Run("values can't repeat in a cage", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Cages: []Cage{
+			{6, []CellRef{{0, 0}, {4, 4}}},
+		}})
+		assert.NoError(t, s.setCell(0, 0, 3))
+		assert.Equal(t, false, s.cells[4][4].hasCandidate(3))
+		_, err := sanityCheck(s)
+		assert.Error(t, err)
+	})
+	t.Run("full cage with the wrong sum", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, Variant{Cages: []Cage{
+			{5, []CellRef{{0, 0}, {4, 4}}},
+		}})
+		assert.NoError(t, s.setCell(0, 0, 1))
+		assert.NoError(t, s.setCell(4, 4, 2))
+		_, err := sanityCheck(s)
+		assert.Error(t, err)
+	})
+}
+
+func assertSolution(t *testing.T, s *SudokuSquare, solution string) {
+	expected, _ := FormatSudoku(solution)
+	result, _ := FormatSudoku(s.String())
+	assert.Equal(t, expected, result)
+}
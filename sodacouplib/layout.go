@@ -0,0 +1,272 @@
+package sodacouplib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Layout is the shape of the board: a grid of Size()×Size() cells split into
+// boxes that are BoxRows cells high and BoxCols cells wide.
+type Layout struct {
+	BoxRows, BoxCols int
+}
+
+// ClassicLayout is the usual 9×9 board with 3×3 boxes.
+var ClassicLayout = Layout{3, 3}
+
+// Values bigger than 9 are written as letters, so 16×16 boards use 1-9 and
+// A-G and 25×25 boards go up to P.
+const symbols = "123456789ABCDEFGHIJKLMNOP"
+
+const maxSize = len(symbols)
+
+// Size is the number of cells along each side, and so the number of values.
+func (l Layout) Size() int {
+	return l.BoxRows * l.BoxCols
+}
+
+func (l Layout) validate() error {
+	if l.BoxRows < 1 || l.BoxCols < 1 || l.Size() > maxSize {
+		return houseError(ErrOutOfRange, "", 0, "unsupported box shape %dx%d", l.BoxRows, l.BoxCols)
+	}
+	return nil
+}
+
+// LayoutForSize picks the usual box shape for a board of the given size:
+// as square as possible, with boxes wider than they are high (so 2×3 for
+// 6×6 and 3×4 for 12×12).
+func LayoutForSize(size int) (Layout, error) {
+	if size < 1 || size > maxSize {
+		return Layout{}, houseError(ErrOutOfRange, "", 0, "unsupported size %d", size)
+	}
+	boxRows := 1
+	for r := 1; r*r <= size; r++ {
+		if size%r == 0 {
+			boxRows = r
+		}
+	}
+	if boxRows == 1 && size > 1 {
+		return Layout{}, houseError(ErrInvalidVariant, "", 0, "size %d can't be split into boxes", size)
+	}
+	return Layout{boxRows, size / boxRows}, nil
+}
+
+// Symbol used to write a value.
+func valueSymbol(val int) byte {
+	return symbols[val-1]
+}
+
+// Value of a written symbol, or 0 if it isn't one.
+func symbolValue(r byte) int {
+	if r >= 'a' && r <= 'z' {
+		r -= 'a' - 'A'
+	}
+	return strings.IndexByte(symbols, r) + 1
+}
+
+// Variant adds rules on top of the rows, columns and boxes every board has.
+// The zero value is plain sudoku.
+type Variant struct {
+	// Cages turn the board into a killer sudoku, see Cage.
+	Cages []Cage
+	// Diagonals adds the two long diagonals as houses (Sudoku X).
+	Diagonals bool
+	// Regions replace the boxes with irregular ones (jigsaw sudoku), giving
+	// the region of each cell by row and column. See ParseRegions.
+	Regions [][]int
+}
+
+func (v Variant) validate(layout Layout) error {
+	if v.Regions != nil {
+		if err := validateRegions(v.Regions, layout.Size()); err != nil {
+			return err
+		}
+	}
+	return validateCages(v.Cages, layout.Size())
+}
+
+// geometry is everything about the board that doesn't change as it's
+// solved. It's worked out once from the Layout and shared between copies of
+// a square.
+type geometry struct {
+	layout  Layout
+	variant Variant
+	size    int
+	houses  []house
+	// cellHouses are the indexes into houses of those each cell is in,
+	// indexed by row*size+col.
+	cellHouses [][]int
+	// blocks are the indexes into houses of the boxes, or of the regions in
+	// jigsaw sudoku. cellBlock is the index into blocks for each cell.
+	blocks    []int
+	cellBlock []int
+	cages     []cage
+	// cellCage is the index into cages of the cage each cell is in, or -1,
+	// indexed by row*size+col.
+	cellCage []int
+	// peers are the cells each cell sees (shares a house or cage with, so
+	// can't hold the same value as), indexed by row*size+col.
+	peers [][]int
+	// peerSets are the peers again, as cellSets.
+	peerSets []cellSet
+}
+
+// A house is a row, column or block (or region), or a diagonal in Sudoku X:
+// size cells that each value has to appear in exactly once.
+type house struct {
+	name  string
+	cells []int // row*size+col of each cell
+}
+
+func newGeometry(layout Layout, variant Variant) *geometry {
+	n := layout.Size()
+	g := &geometry{layout: layout, variant: variant, size: n}
+
+	for row := 0; row < n; row++ {
+		h := house{name: fmt.Sprintf("row %d", row)}
+		for col := 0; col < n; col++ {
+			h.cells = append(h.cells, row*n+col)
+		}
+		g.houses = append(g.houses, h)
+	}
+
+	for col := 0; col < n; col++ {
+		h := house{name: fmt.Sprintf("column %d", col)}
+		for row := 0; row < n; row++ {
+			h.cells = append(h.cells, row*n+col)
+		}
+		g.houses = append(g.houses, h)
+	}
+
+	g.cellBlock = make([]int, n*n)
+	if variant.Regions != nil {
+		regions := make([]house, n)
+		for row := 0; row < n; row++ {
+			for col := 0; col < n; col++ {
+				r := variant.Regions[row][col]
+				regions[r].cells = append(regions[r].cells, row*n+col)
+				g.cellBlock[row*n+col] = r
+			}
+		}
+		for r, h := range regions {
+			h.name = regionName(r)
+			g.blocks = append(g.blocks, len(g.houses))
+			g.houses = append(g.houses, h)
+		}
+	} else {
+		for si := 0; si < n; si += layout.BoxRows {
+			for sj := 0; sj < n; sj += layout.BoxCols {
+				h := house{name: fmt.Sprintf("block %d %d", si/layout.BoxRows, sj/layout.BoxCols)}
+				for row := si; row < si+layout.BoxRows; row++ {
+					for col := sj; col < sj+layout.BoxCols; col++ {
+						h.cells = append(h.cells, row*n+col)
+						g.cellBlock[row*n+col] = len(g.blocks)
+					}
+				}
+				g.blocks = append(g.blocks, len(g.houses))
+				g.houses = append(g.houses, h)
+			}
+		}
+	}
+
+	if variant.Diagonals {
+		diag, anti := house{name: "diagonal"}, house{name: "anti-diagonal"}
+		for i := 0; i < n; i++ {
+			diag.cells = append(diag.cells, i*n+i)
+			anti.cells = append(anti.cells, i*n+n-1-i)
+		}
+		g.houses = append(g.houses, diag, anti)
+	}
+
+	g.cellHouses = make([][]int, n*n)
+	for i, h := range g.houses {
+		for _, idx := range h.cells {
+			g.cellHouses[idx] = append(g.cellHouses[idx], i)
+		}
+	}
+
+	g.cellCage = make([]int, n*n)
+	for i := range g.cellCage {
+		g.cellCage[i] = -1
+	}
+	for i, c := range variant.Cages {
+		cg := cage{name: cageName(i), sum: c.Sum}
+		for _, ref := range c.Cells {
+			idx := ref.Row*n + ref.Col
+			cg.cells = append(cg.cells, idx)
+			g.cellCage[idx] = i
+		}
+		g.cages = append(g.cages, cg)
+	}
+
+	g.peers = make([][]int, n*n)
+	for idx := range g.peers {
+		seen := map[int]bool{idx: true}
+		add := func(cells []int) {
+			for _, other := range cells {
+				if !seen[other] {
+					seen[other] = true
+					g.peers[idx] = append(g.peers[idx], other)
+				}
+			}
+		}
+		for _, h := range g.cellHouses[idx] {
+			add(g.houses[h].cells)
+		}
+		if c := g.cellCage[idx]; c >= 0 {
+			add(g.cages[c].cells)
+		}
+		sort.Ints(g.peers[idx])
+	}
+
+	g.peerSets = make([]cellSet, n*n)
+	for idx, peers := range g.peers {
+		g.peerSets[idx] = newCellSet(n * n)
+		for _, other := range peers {
+			g.peerSets[idx].add(other)
+		}
+	}
+	return g
+}
+
+// Whether two different cells see each other, see geometry.peers.
+func (g *geometry) sees(a, b int) bool {
+	return g.peerSets[a].has(b)
+}
+
+// Whether the board is just rows, columns and boxes, with no variant rules.
+func (g *geometry) isPlain() bool {
+	return len(g.variant.Cages) == 0 && !g.variant.Diagonals && g.variant.Regions == nil
+}
+
+// Box shape to draw the board with. Regions don't line up with any, so
+// jigsaw boards are drawn as one big box.
+func (g *geometry) drawnBox() (int, int) {
+	if g.variant.Regions != nil {
+		return g.size, g.size
+	}
+	return g.layout.BoxRows, g.layout.BoxCols
+}
+
+// The kinds of house, going by where newGeometry puts them.
+const (
+	rowHouse = iota
+	columnHouse
+	blockHouse
+	diagonalHouse
+)
+
+// Which kind of house houses[h] is.
+func (g *geometry) houseKind(h int) int {
+	switch {
+	case h < g.size:
+		return rowHouse
+	case h < 2*g.size:
+		return columnHouse
+	case h < 2*g.size+len(g.blocks):
+		return blockHouse
+	default:
+		return diagonalHouse
+	}
+}
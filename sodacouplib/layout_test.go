@@ -0,0 +1,186 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestLayoutForSize(t *testing.T) {
+	for size, expected := range map[int]Layout{
+		4:  {2, 2},
+		6:  {2, 3},
+		9:  {3, 3},
+		12: {3, 4},
+		16: {4, 4},
+		25: {5, 5},
+	} {
+		layout, err := LayoutForSize(size)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, layout)
+		assert.Equal(t, size, layout.Size())
+	}
+	for _, size := range []int{0, 5, 7, 26} {
+		_, err := LayoutForSize(size)
+		assert.Error(t, err, "size %d", size)
+	}
+}
+
+func TestOtherSizes(t *testing.T) {
+	t.Run("4x4", func(t *testing.T) {
+		s, err := NewSudokuSquare(`
+			1_ _4
+			__ 1_
+
+			_1 __
+			4_ _1
+		`)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+		assert.Equal(t, ` -------------
+ | 1 _ | _ 4 |
+ | _ _ | 1 _ |
+ -------------
+ | _ 1 | _ _ |
+ | 4 _ | _ 1 |
+ -------------
+`, s.String())
+
+		_, err = s.Solve(SolveOptions{})
+		if err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+		expected, _ := FormatSudoku(`
+			12 34
+			34 12
+			21 43
+			43 21
+		`)
+		result, _ := FormatSudoku(s.String())
+		assert.Equal(t, expected, result)
+	})
+	t.Run("6x6 has 2x3 boxes", func(t *testing.T) {
+		testSolvingLayout(t, Layout{2, 3})
+	})
+	t.Run("6x6 with 3x2 boxes", func(t *testing.T) {
+		testSolvingLayout(t, Layout{3, 2})
+	})
+	t.Run("12x12", func(t *testing.T) {
+		testSolvingLayout(t, Layout{3, 4})
+	})
+	t.Run("16x16 uses letters", func(t *testing.T) {
+		testSolvingLayout(t, Layout{4, 4})
+	})
+	t.Run("letters are case insensitive", func(t *testing.T) {
+		upper, err := NewSudokuSquare(patternGrid(Layout{4, 4}, 0))
+		assert.NoError(t, err)
+		lower, err := NewSudokuSquare(strings.ToLower(patternGrid(Layout{4, 4}, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, upper.String(), lower.String())
+	})
+	t.Run("formats by box", func(t *testing.T) {
+		result, err := FormatSudoku("123456" + "456123" + "231564" + "564231" + "312645" + "645312")
+		assert.NoError(t, err)
+		assert.Equal(t, "123 456\n456 123\n\n231 564\n564 231\n\n312 645\n645 312\n", result)
+	})
+	t.Run("value too big for the board", func(t *testing.T) {
+		_, err := NewSudokuSquare("1234" + "5___" + "____" + "____")
+		assert.Error(t, err)
+	})
+	t.Run("wrong box shape for the board", func(t *testing.T) {
+		_, err := NewSudokuSquareWithLayout(patternGrid(Layout{2, 2}, 0), Layout{2, 3})
+		assert.Error(t, err)
+		_, err = NewSudokuSquareWithLayout(patternGrid(Layout{2, 2}, 0), Layout{0, 4})
+		assert.Error(t, err)
+	})
+}
+
+// patternGrid writes out a solved grid for the layout, shifting each row
+// along so every row, column and box holds each value once. Every `blank`th
+// cell is left empty, none if it's 0.
+func patternGrid(layout Layout, blank int) string {
+	n, br, bc := layout.Size(), layout.BoxRows, layout.BoxCols
+	var sb strings.Builder
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			if blank > 0 && (r*n+c)%blank == 0 {
+				sb.WriteByte('_')
+			} else {
+				sb.WriteByte(valueSymbol((bc*(r%br)+r/br+c)%n + 1))
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func testSolvingLayout(t *testing.T, layout Layout) {
+	s, err := NewSudokuSquareWithLayout(patternGrid(layout, 3), layout)
+	if err != nil {
+		t.Fatal("got unexpected error from valid input:", err)
+	}
+	_, err = s.Solve(SolveOptions{})
+	if err != nil {
+		t.Fatal("got unexpected error from solving:", err)
+	}
+	expected, _ := NewSudokuSquareWithLayout(patternGrid(layout, 0), layout)
+	assert.Equal(t, expected.String(), s.String())
+}
+
+func TestDiagonals(t *testing.T) {
+	variant := Variant{Diagonals: true}
+	t.Run("setting a cell rules it out along its diagonals", func(t *testing.T) {
+		s, err := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, variant)
+		assert.NoError(t, err)
+		assert.NoError(t, s.setCell(4, 4, 5))
+		for i := 0; i < 9; i++ {
+			assert.Equal(t, false, s.cells[i][i].hasCandidate(5))
+			assert.Equal(t, false, s.cells[i][8-i].hasCandidate(5))
+		}
+		assert.Equal(t, true, s.cells[0][1].hasCandidate(5))
+
+		assert.Equal(t, false, isValidMove(copyFrom(s), 8, 0, 5))
+		assert.Equal(t, true, isValidMove(copyFrom(s), 8, 1, 5))
+	})
+	t.Run("hidden single along a diagonal", func(t *testing.T) {
+		s, _ := NewSudokuSquareWithVariant(emptyClassic, ClassicLayout, variant)
+		for i := 1; i < 9; i++ {
+			s.cells[i][i].removeCandidate(7)
+		}
+
+		var steps []Step
+		_, err := hiddenSingle(s, func(st Step) bool {
+			steps = append(steps, st)
+			return true
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []Step{{
+			Technique:  "Hidden Single",
+			House:      "diagonal",
+			Cells:      []CellRef{{0, 0}},
+			Placements: []Candidate{{0, 0, 7}},
+		}}, steps)
+	})
+	t.Run("generated puzzles keep the diagonals", func(t *testing.T) {
+		s, err := GenerateProblemWithVariant(ClassicLayout, variant)
+		if err != nil {
+			t.Fatal("failed to generate:", err)
+		}
+		unique, err := IsUnique(s)
+		assert.NoError(t, err)
+		assert.Equal(t, true, unique)
+
+		_, err = s.Solve(SolveOptions{})
+		if err != nil {
+			t.Fatal("failed to solve:", err)
+		}
+		diag, anti := map[byte]bool{}, map[byte]bool{}
+		for i := 0; i < 9; i++ {
+			diag[s.cells[i][i].value] = true
+			anti[s.cells[i][8-i].value] = true
+		}
+		assert.Equal(t, 9, len(diag))
+		assert.Equal(t, 9, len(anti))
+	})
+}
@@ -0,0 +1,299 @@
+package sodacouplib
+
+import (
+	"errors"
+)
+
+// LinkGraph is how the cells that could still hold Digit are tied together.
+// Two cells are strongly linked when they're the last two places for the
+// digit in some house, so one of them has to hold it, and weakly linked when
+// they just see each other, so at most one of them can.
+type LinkGraph struct {
+	Digit int
+	Cells []CellRef
+	// Links has one entry for each pair of cells that see each other.
+	Links []Link
+
+	cells []int // row*size+col of each of Cells
+	// strong are the cells each cell is strongly linked to, linked those it
+	// has any link to, both as indexes into Cells.
+	strong, linked [][]int
+}
+
+// Link joins two cells of a LinkGraph.
+type Link struct {
+	A, B   CellRef
+	Strong bool
+	// House is the house (or cage) the cells are linked through, the one
+	// they're the last two places in for a strong link.
+	House string
+}
+
+// LinkGraph works out the links between the cells that could still hold
+// digit.
+func (sud *SudokuSquare) LinkGraph(digit int) *LinkGraph {
+	n := sud.size()
+	geo := sud.geo
+	g := &LinkGraph{Digit: digit}
+	node := make(map[int]int)
+	for idx := 0; idx < n*n; idx++ {
+		if sud.cellAt(idx).hasCandidate(digit) {
+			node[idx] = len(g.cells)
+			g.cells = append(g.cells, idx)
+			g.Cells = append(g.Cells, CellRef{idx / n, idx % n})
+		}
+	}
+	places := make([]int, len(geo.houses))
+	for _, idx := range g.cells {
+		for _, h := range geo.cellHouses[idx] {
+			places[h]++
+		}
+	}
+
+	g.strong = make([][]int, len(g.cells))
+	g.linked = make([][]int, len(g.cells))
+	for i, a := range g.cells {
+		for _, b := range geo.peers[a] {
+			j, ok := node[b]
+			if !ok || j < i {
+				continue
+			}
+			link := Link{A: g.Cells[i], B: g.Cells[j]}
+			for _, h := range geo.cellHouses[a] {
+				if !containsInt(geo.cellHouses[b], h) {
+					continue
+				}
+				if link.House == "" || (!link.Strong && places[h] == 2) {
+					link.House = geo.houses[h].name
+					link.Strong = places[h] == 2
+				}
+			}
+			if link.House == "" {
+				link.House = geo.cages[geo.cellCage[a]].name
+			}
+			g.Links = append(g.Links, link)
+			g.linked[i] = append(g.linked[i], j)
+			g.linked[j] = append(g.linked[j], i)
+			if link.Strong {
+				g.strong[i] = append(g.strong[i], j)
+				g.strong[j] = append(g.strong[j], i)
+			}
+		}
+	}
+	return g
+}
+
+// Splits the cells into clusters joined up by strong links, colouring each
+// cluster with two colours so strongly linked cells always differ. color is
+// 0 for cells in no cluster (no strong links), otherwise 1 or 2.
+func (g *LinkGraph) clusters() (clusters [][]int, color []int, err error) {
+	color = make([]int, len(g.cells))
+	for i := range g.cells {
+		if color[i] != 0 || len(g.strong[i]) == 0 {
+			continue
+		}
+		color[i] = 1
+		cluster := []int{i}
+		for k := 0; k < len(cluster); k++ {
+			c := cluster[k]
+			for _, j := range g.strong[c] {
+				switch color[j] {
+				case 0:
+					color[j] = 3 - color[c]
+					cluster = append(cluster, j)
+				case color[c]:
+					// exactly one of each strong pair holds the digit, which
+					// an odd loop of them can't manage
+					return nil, nil, cellError(ErrContradiction, g.Cells[c].Row, g.Cells[c].Col, g.Digit, "",
+						"%d can't be placed in the cells strongly linked to %d,%d", g.Digit, g.Cells[c].Row, g.Cells[c].Col)
+				}
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, color, nil
+}
+
+// Colours each cluster of strongly linked cells for a digit: one colour or
+// the other has the digit throughout. If two cells of the same colour see
+// each other (a wrap), that colour is wrong and the digit goes from all of
+// its cells. Otherwise a cell outside the cluster that sees both colours (a
+// trap) can't have the digit.
+func simpleColoring(sud *SudokuSquare, step StepFunc) (bool, error) {
+	changes := false
+	for digit := 1; digit <= sud.size(); digit++ {
+		g := sud.LinkGraph(digit)
+		clusters, color, err := g.clusters()
+		if err != nil {
+			return changes, err
+		}
+		for _, cluster := range clusters {
+			st := colorWrap(sud, g, cluster, color)
+			if len(st.Eliminations) == 0 {
+				st = colorTrap(sud, g, cluster, color)
+			}
+			if len(st.Eliminations) > 0 {
+				changes = true
+				if e := report(step, st); e != nil {
+					return true, e
+				}
+				break // the graph's out of date now
+			}
+		}
+	}
+	return changes, nil
+}
+
+func colorWrap(sud *SudokuSquare, g *LinkGraph, cluster []int, color []int) Step {
+	st := Step{Technique: "Color Wrap"}
+	for _, i := range cluster {
+		st.addCell(sud.cellAt(g.cells[i]))
+	}
+	for _, i := range cluster {
+		for _, j := range g.linked[i] {
+			if color[j] != color[i] || !containsInt(cluster, j) {
+				continue
+			}
+			for _, k := range cluster {
+				if color[k] == color[i] {
+					st.eliminate(sud.cellAt(g.cells[k]), g.Digit)
+				}
+			}
+			return st
+		}
+	}
+	return st
+}
+
+func colorTrap(sud *SudokuSquare, g *LinkGraph, cluster []int, color []int) Step {
+	st := Step{Technique: "Color Trap"}
+	for _, i := range cluster {
+		st.addCell(sud.cellAt(g.cells[i]))
+	}
+	for i := range g.cells {
+		if containsInt(cluster, i) {
+			continue
+		}
+		var seen [3]bool
+		for _, j := range g.linked[i] {
+			if containsInt(cluster, j) {
+				seen[color[j]] = true
+			}
+		}
+		if seen[1] && seen[2] {
+			st.eliminate(sud.cellAt(g.cells[i]), g.Digit)
+		}
+	}
+	return st
+}
+
+// The longest X-Cycle looked for, in links.
+const maxCycleLength = 8
+
+// errCycleFound stops the cycle search for a digit once it's changed the
+// square, as the digit's graph is then out of date.
+var errCycleFound = errors.New("cycle found")
+
+// An X-Cycle is a loop of cells for one digit whose links alternate between
+// strong and weak (a strong link will do for a weak one). If it goes all
+// the way round alternating, one or other half of the cells holds the digit
+// and each weak link acts as a strong one, so the digit goes from any other
+// cell seeing both ends of one. If it meets itself with two strong links the
+// cell there has the digit, and with two weak ones it can't.
+func xCycle(sud *SudokuSquare, step StepFunc) (bool, error) {
+	changes := false
+	for digit := 1; digit <= sud.size(); digit++ {
+		g := sud.LinkGraph(digit)
+		for start := range g.cells {
+			var err error
+			for _, firstStrong := range []bool{true, false} {
+				if err = xCycleFrom(sud, step, g, start, firstStrong); err != nil {
+					break
+				}
+			}
+			if err == errCycleFound {
+				changes = true
+				break
+			}
+			if err != nil {
+				return true, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Walks every alternating path from start, its first link strong or weak,
+// and acts on any that get back to it.
+func xCycleFrom(sud *SudokuSquare, step StepFunc, g *LinkGraph, start int, firstStrong bool) error {
+	path := []int{start}
+	onPath := make([]bool, len(g.cells))
+	onPath[start] = true
+	var walk func() error
+	walk = func() error {
+		k := len(path) - 1 // links so far
+		strong := (k%2 == 0) == firstStrong
+		next := g.linked[path[k]]
+		if strong {
+			next = g.strong[path[k]]
+		}
+		for _, j := range next {
+			if j == start && k >= 2 {
+				if err := xCycleStep(sud, step, g, path, firstStrong, strong); err != nil {
+					return err
+				}
+				continue
+			}
+			if onPath[j] || len(path) == maxCycleLength {
+				continue
+			}
+			path = append(path, j)
+			onPath[j] = true
+			if err := walk(); err != nil {
+				return err
+			}
+			onPath[j] = false
+			path = path[:len(path)-1]
+		}
+		return nil
+	}
+	return walk()
+}
+
+// Acts on a cycle found by xCycleFrom, whose last link back to the start is
+// strong or not. Returns errCycleFound if that changed anything.
+func xCycleStep(sud *SudokuSquare, step StepFunc, g *LinkGraph, path []int, firstStrong, lastStrong bool) error {
+	geo := sud.geo
+	st := Step{Technique: "X-Cycle"}
+	for _, i := range path {
+		st.addCell(sud.cellAt(g.cells[i]))
+	}
+	start := sud.cellAt(g.cells[path[0]])
+	switch {
+	case firstStrong && lastStrong:
+		if err := st.place(sud, start, g.Digit); err != nil {
+			return err
+		}
+	case !firstStrong && !lastStrong:
+		st.eliminate(start, g.Digit)
+	case firstStrong:
+		// the weak links are the odd ones, the last being back to the start
+		for w := 1; w < len(path); w += 2 {
+			b := g.cells[path[(w+1)%len(path)]]
+			for _, j := range g.linked[path[w]] {
+				if !containsInt(path, j) && geo.sees(g.cells[j], b) {
+					st.eliminate(sud.cellAt(g.cells[j]), g.Digit)
+				}
+			}
+		}
+	default:
+		return nil // the same loop as the other way round
+	}
+	if len(st.Placements) == 0 && len(st.Eliminations) == 0 {
+		return nil
+	}
+	if e := report(step, st); e != nil {
+		return e
+	}
+	return errCycleFound
+}
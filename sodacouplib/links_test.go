@@ -0,0 +1,97 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLinkGraph(t *testing.T) {
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	g := s.LinkGraph(1)
+	assert.Equal(t, 1, g.Digit)
+	assert.Equal(t, 81-7, len(g.Cells))
+
+	var fromCorner []Link
+	for _, l := range g.Links {
+		if l.A == (CellRef{0, 0}) {
+			fromCorner = append(fromCorner, l)
+		}
+	}
+	assert.Equal(t, Link{CellRef{0, 0}, CellRef{0, 4}, true, "row 0"}, fromCorner[0])
+	assert.Equal(t, Link{CellRef{0, 0}, CellRef{1, 0}, false, "column 0"}, fromCorner[1])
+	assert.Equal(t, Link{CellRef{0, 0}, CellRef{1, 1}, false, "block 0 0"}, fromCorner[2])
+	assert.Equal(t, 13, len(fromCorner))
+}
+
+func TestColorWrap(t *testing.T) {
+	// a chain of strong links round to a cell in the same box as its start
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	keepOnly(s, 1, colRefs(4), CellRef{0, 4}, CellRef{4, 4})
+	keepOnly(s, 1, rowRefs(4), CellRef{4, 4}, CellRef{4, 1})
+	keepOnly(s, 1, colRefs(1), CellRef{4, 1}, CellRef{1, 1})
+
+	assert.Equal(t, []Step{{
+		Technique:    "Color Wrap",
+		Cells:        []CellRef{{0, 0}, {0, 4}, {4, 4}, {4, 1}, {1, 1}},
+		Eliminations: []Candidate{{0, 0, 1}, {4, 4, 1}, {1, 1, 1}},
+	}}, collectSteps(t, simpleColoring, s))
+}
+
+func TestColorTrap(t *testing.T) {
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	box := []CellRef{{0, 3}, {0, 4}, {0, 5}, {1, 3}, {1, 4}, {1, 5}, {2, 3}, {2, 4}, {2, 5}}
+	keepOnly(s, 1, box, CellRef{0, 4}, CellRef{2, 3})
+	keepOnly(s, 1, colRefs(3), CellRef{2, 3}, CellRef{6, 3})
+
+	assert.Equal(t, []Step{{
+		Technique:    "Color Trap",
+		Cells:        []CellRef{{0, 0}, {0, 4}, {2, 3}, {6, 3}},
+		Eliminations: []Candidate{{6, 0, 1}},
+	}}, collectSteps(t, simpleColoring, s))
+	noOpCheck(t, simpleColoring, s)
+}
+
+func TestColoringContradiction(t *testing.T) {
+	// an odd loop of strong links can't alternate
+	s := newEmptySudoku()
+	box := []CellRef{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}
+	keepOnly(s, 1, box, CellRef{0, 0}, CellRef{1, 1})
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	keepOnly(s, 1, colRefs(4), CellRef{0, 4}, CellRef{4, 4})
+	keepOnly(s, 1, rowRefs(4), CellRef{4, 4}, CellRef{4, 1})
+	keepOnly(s, 1, colRefs(1), CellRef{4, 1}, CellRef{1, 1})
+	_, err := simpleColoring(s, ignoreSteps)
+	assert.Error(t, err)
+}
+
+func TestXCycle(t *testing.T) {
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	keepOnly(s, 1, rowRefs(4), CellRef{4, 0}, CellRef{4, 4})
+
+	steps := collectSteps(t, xCycle, s)
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, "X-Cycle", steps[0].Technique)
+	assert.Equal(t, []CellRef{{0, 0}, {0, 4}, {4, 4}, {4, 0}}, steps[0].Cells)
+	assert.Equal(t, 14, len(steps[0].Eliminations))
+	assert.Contains(t, steps[0].Eliminations, Candidate{1, 4, 1})
+	assert.Contains(t, steps[0].Eliminations, Candidate{8, 0, 1})
+	noOpCheck(t, xCycle, s)
+
+	t.Run("two strong links meeting place the value", func(t *testing.T) {
+		s := newEmptySudoku()
+		keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+		keepOnly(s, 1, rowRefs(4), CellRef{4, 4}, CellRef{4, 1})
+		box := []CellRef{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}
+		keepOnly(s, 1, box, CellRef{0, 0}, CellRef{1, 1})
+
+		assert.Equal(t, []Step{{
+			Technique:  "X-Cycle",
+			Cells:      []CellRef{{0, 0}, {0, 4}, {4, 4}, {4, 1}, {1, 1}},
+			Placements: []Candidate{{0, 0, 1}},
+		}}, collectSteps(t, xCycle, s))
+	})
+}
@@ -0,0 +1,128 @@
+package sodacouplib
+
+// 3D Medusa is simple coloring across digits: candidates are joined by the
+// strong links of the chain graph, bivalue cells as well as conjugate
+// pairs, and each cluster of them coloured with two colours so strongly
+// linked candidates always differ. One colour or the other is true
+// throughout. So:
+//
+//   - a colour weakly linked to itself (twice in a cell, or a digit twice
+//     in a house) is false, and its candidates can all go.
+//   - a colour that would leave a cell with nothing, every candidate left in
+//     it weakly linked to the colour, is false as well.
+//   - any other candidate weakly linked to both colours can go.
+func medusa(sud *SudokuSquare, step StepFunc) (bool, error) {
+	changes := false
+	for {
+		st, err := findMedusa(newChainGraph(sud))
+		if err != nil || st == nil {
+			return changes, err
+		}
+		changes = true
+		if e := report(step, *st); e != nil {
+			return true, e
+		}
+	}
+}
+
+// Colours the chain graph's clusters in turn, and makes the step for the
+// first that can take anything out.
+func findMedusa(cg *chainGraph) (*Step, error) {
+	sud := cg.sud
+	n := sud.size()
+	color := make([]int, len(cg.strong)) // 0 for uncoloured, otherwise 1 or 2
+	for start := range cg.strong {
+		if color[start] != 0 || len(cg.strong[start]) == 0 {
+			continue
+		}
+		color[start] = 1
+		cluster := []int{start}
+		for k := 0; k < len(cluster); k++ {
+			c := cluster[k]
+			for _, j := range cg.strong[c] {
+				switch color[j] {
+				case 0:
+					color[j] = 3 - color[c]
+					cluster = append(cluster, j)
+				case color[c]:
+					cell, digit := cg.node(chainState(c, true))
+					return nil, cellError(ErrContradiction, cell.row, cell.col, digit, "",
+						"%d,%d can be neither %d nor not %d", cell.row, cell.col, digit, digit)
+				}
+			}
+		}
+
+		in := make(map[int]bool, len(cluster))
+		for _, c := range cluster {
+			in[c] = true
+		}
+		// which colours of the cluster each candidate is weakly linked to
+		sees := func(node int) (seen [3]bool) {
+			for _, j := range cg.weak[node] {
+				if in[j] {
+					seen[color[j]] = true
+				}
+			}
+			return seen
+		}
+
+		st := Step{Technique: "3D Medusa"}
+		for _, c := range cluster {
+			if cell, _ := cg.node(chainState(c, true)); !containsRef(st.Cells, CellRef{cell.row, cell.col}) {
+				st.addCell(cell)
+			}
+		}
+		if wrong := medusaContradiction(cg, cluster, color, sees); wrong != 0 {
+			for _, c := range cluster {
+				if color[c] == wrong {
+					cell, digit := cg.node(chainState(c, true))
+					st.eliminate(cell, digit)
+				}
+			}
+			return &st, nil
+		}
+		for node := 0; node < n*n*n; node++ {
+			cell, digit := cg.node(chainState(node, true))
+			if !in[node] && cell.hasCandidate(digit) {
+				if seen := sees(node); seen[1] && seen[2] {
+					st.eliminate(cell, digit)
+				}
+			}
+		}
+		if len(st.Eliminations) > 0 {
+			return &st, nil
+		}
+		for _, c := range cluster {
+			color[c] = -1 // done with, so not coloured again
+		}
+	}
+	return nil, nil
+}
+
+// The colour of the cluster that can't be true, or 0 if neither is found
+// to be wrong.
+func medusaContradiction(cg *chainGraph, cluster []int, color []int, sees func(int) [3]bool) int {
+	for _, c := range cluster {
+		if sees(c)[color[c]] {
+			return color[c]
+		}
+	}
+	n := cg.sud.size()
+	for idx := 0; idx < n*n; idx++ {
+		digits := cellDigits(cg.sud.cellAt(idx))
+		if len(digits) == 0 {
+			continue
+		}
+		for wrong := 1; wrong <= 2; wrong++ {
+			emptied := true
+			for _, digit := range digits {
+				node := idx*n + digit - 1
+				emptied = emptied && color[node] != 3-wrong && sees(node)[wrong]
+			}
+			if emptied {
+				return wrong
+			}
+		}
+	}
+	return 0
+}
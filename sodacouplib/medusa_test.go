@@ -0,0 +1,54 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMedusaWrap(t *testing.T) {
+	// 1 in row 0, 2 in column 4 and 3 in row 4 and column 0, joined by the
+	// bivalue cells 0,4 and 4,4, colour 1 and 3 of the corner the same
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	s.cells[0][4].candidates = candidatesOf(1, 2)
+	keepOnly(s, 2, colRefs(4), CellRef{0, 4}, CellRef{4, 4})
+	s.cells[4][4].candidates = candidatesOf(2, 3)
+	keepOnly(s, 3, rowRefs(4), CellRef{4, 4}, CellRef{4, 0})
+	keepOnly(s, 3, colRefs(0), CellRef{4, 0}, CellRef{0, 0})
+
+	steps := collectSteps(t, medusa, s)
+	assert.Equal(t, Step{
+		Technique:    "3D Medusa",
+		Cells:        []CellRef{{0, 0}, {0, 4}, {4, 4}, {4, 0}},
+		Eliminations: []Candidate{{0, 0, 1}, {0, 4, 2}, {4, 4, 3}, {0, 0, 3}},
+	}, steps[0])
+}
+
+func TestMedusaTrap(t *testing.T) {
+	// 1 and 2 both in row 0 only at 0,0 and the bivalue 0,4, so the corner
+	// has one colour for 1 and the other for 2
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	keepOnly(s, 2, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	s.cells[0][4].candidates = candidatesOf(1, 2)
+
+	steps := collectSteps(t, medusa, s)
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, "3D Medusa", steps[0].Technique)
+	assert.Equal(t, 7, len(steps[0].Eliminations))
+	assert.Equal(t, candidatesOf(1, 2), s.cells[0][0].candidates)
+	noOpCheck(t, medusa, s)
+}
+
+func TestMedusaContradiction(t *testing.T) {
+	// an odd loop of strong links can't alternate, here or in simple coloring
+	s := newEmptySudoku()
+	box := []CellRef{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}
+	keepOnly(s, 1, box, CellRef{0, 0}, CellRef{1, 1})
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	keepOnly(s, 1, colRefs(4), CellRef{0, 4}, CellRef{4, 4})
+	keepOnly(s, 1, rowRefs(4), CellRef{4, 4}, CellRef{4, 1})
+	keepOnly(s, 1, colRefs(1), CellRef{4, 1}, CellRef{1, 1})
+	_, err := medusa(s, ignoreSteps)
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package sodacouplib
+
+import "context"
+
+// IsMinimal reports whether the problem has a unique solution that taking
+// away any one of its clues would lose.
+func IsMinimal(sud *SudokuSquare) (bool, error) {
+	return IsMinimalContext(context.Background(), sud, SolveOptions{})
+}
+
+// IsMinimalContext is IsMinimal, giving up with a *BudgetError once ctx is
+// done or opts.MaxNodes have been used. The other options aren't used.
+func IsMinimalContext(ctx context.Context, sud *SudokuSquare, opts SolveOptions) (bool, error) {
+	b := newBudget(ctx, opts.MaxNodes)
+	if n, err := countSolutionsWithin(sud, 2, b); n != 1 || err != nil {
+		return false, err
+	}
+	cells := copyFrom(sud)
+	for row := 0; row < sud.size(); row++ {
+		for col := 0; col < sud.size(); col++ {
+			if !isSet(cells.at(row, col)) {
+				continue
+			}
+			removable, err := isRemovable(cells, row, col, b)
+			if removable || err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// Minimize takes away clues, in row-major order, for as long as the problem
+// keeps a unique solution. Going once through is enough: taking more clues
+// away only adds solutions, so a clue that couldn't be removed earlier
+// can't be removed later either.
+func Minimize(sud *SudokuSquare) error {
+	return MinimizeContext(context.Background(), sud, SolveOptions{})
+}
+
+// MinimizeContext is Minimize, giving up with a *BudgetError once ctx is
+// done or opts.MaxNodes have been used, in which case the problem is left
+// as it was. The other options aren't used.
+func MinimizeContext(ctx context.Context, sud *SudokuSquare, opts SolveOptions) error {
+	b := newBudget(ctx, opts.MaxNodes)
+	n, err := countSolutionsWithin(sud, 2, b)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return houseError(ErrNoSolution, "", 0, "can only minimize a problem with a unique solution")
+	} else if n > 1 {
+		return houseError(ErrNotUnique, "", 0, "can only minimize a problem with a unique solution")
+	}
+	cells := copyFrom(sud)
+	for row := 0; row < sud.size(); row++ {
+		for col := 0; col < sud.size(); col++ {
+			if !isSet(cells.at(row, col)) {
+				continue
+			}
+			removable, err := isRemovable(cells, row, col, b)
+			if err != nil {
+				return err
+			}
+			if removable {
+				cells.set(row, col, 0)
+			}
+		}
+	}
+	*sud = *cells.toSquare()
+	return nil
+}
+
+// Whether the clue at row,col can go without losing uniqueness. Leaves
+// cells as it found them. Errors only if b runs out.
+func isRemovable(cells grid, row, col int, b *budget) (bool, error) {
+	val := cells.at(row, col)
+	cells.set(row, col, 0)
+	sud := cells.toSquare()
+	cells.set(row, col, val)
+	n, err := countSolutionsWithin(sud, 2, b)
+	return n == 1, err
+}
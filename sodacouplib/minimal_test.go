@@ -0,0 +1,119 @@
+package sodacouplib
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const fullGrid = `
+	185 962 374
+	743 581 962
+	692 473 851
+
+	928 357 146
+	574 816 293
+	316 294 587
+
+	239 648 715
+	451 729 638
+	867 135 429
+`
+
+func TestIsMinimal(t *testing.T) {
+	t.Run("full grid is not minimal", func(t *testing.T) {
+		s, err := NewSudokuSquare(fullGrid)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+		minimal, err := IsMinimal(s)
+		assert.NoError(t, err)
+		assert.Equal(t, false, minimal)
+	})
+	t.Run("problem with several solutions is not minimal", func(t *testing.T) {
+		s, err := NewSudokuSquare(`
+			185 962 374
+			743 581 962
+			692 473 __1
+
+			928 357 146
+			574 816 293
+			316 294 __7
+
+			239 648 715
+			451 729 638
+			867 135 429
+		`)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+		minimal, err := IsMinimal(s)
+		assert.NoError(t, err)
+		assert.Equal(t, false, minimal)
+	})
+}
+
+func TestMinimize(t *testing.T) {
+	t.Run("minimize full grid", func(t *testing.T) {
+		s, err := NewSudokuSquare(fullGrid)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+
+		if err := Minimize(s); err != nil {
+			t.Fatal("got unexpected error minimizing:", err)
+		}
+
+		assertMinimalWithSolution(t, s, fullGrid)
+	})
+	t.Run("minimize is deterministic", func(t *testing.T) {
+		s1, _ := NewSudokuSquare(fullGrid)
+		s2, _ := NewSudokuSquare(fullGrid)
+		assert.NoError(t, Minimize(s1))
+		assert.NoError(t, Minimize(s2))
+		assert.Equal(t, s1.String(), s2.String())
+	})
+	t.Run("can't minimize without unique solution", func(t *testing.T) {
+		assert.Error(t, Minimize(newEmptySudoku()))
+	})
+}
+
+func TestMinimalContext(t *testing.T) {
+	s, _ := NewSudokuSquare(seventeenClues)
+	_, err := IsMinimalContext(context.Background(), s, SolveOptions{MaxNodes: 5})
+	assert.Equal(t, true, errors.Is(err, ErrNodeLimit))
+	minimal, err := IsMinimalContext(context.Background(), s, SolveOptions{MaxNodes: 100000})
+	assert.NoError(t, err)
+	assert.Equal(t, true, minimal)
+
+	s, _ = NewSudokuSquare(fullGrid)
+
+	err = MinimizeContext(context.Background(), s, SolveOptions{MaxNodes: 20})
+	assert.Equal(t, true, errors.Is(err, ErrNodeLimit))
+	assert.Equal(t, 81, s.SetCount(), "giving up should leave the problem alone")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = MinimizeContext(ctx, s, SolveOptions{})
+	assert.Equal(t, true, errors.Is(err, context.Canceled))
+}
+
+func assertMinimalWithSolution(t *testing.T, s *SudokuSquare, solution string) {
+	minimal, err := IsMinimal(s)
+	assert.NoError(t, err)
+	assert.Equal(t, true, minimal)
+
+	// every clue left should come from the solution
+	expected, err := NewSudokuSquare(solution)
+	if err != nil {
+		t.Fatal("got unexpected error from valid input:", err)
+	}
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			if s.cells[r][c].isSet {
+				assert.Equal(t, expected.cells[r][c].value, s.cells[r][c].value)
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package sodacouplib
+
+// A strong link (or conjugate pair) is a house with only two cells left for
+// a value, so one or other of them has to be it.
+type strongLink struct {
+	house int
+	a, b  int // row*size+col of the two cells
+}
+
+// Every strong link for val, in house order.
+func strongLinks(sud *SudokuSquare, val int) []strongLink {
+	var links []strongLink
+	for h, hs := range sud.geo.houses {
+		var found []int
+		for _, idx := range hs.cells {
+			if sud.cellAt(idx).hasCandidate(val) {
+				found = append(found, idx)
+			}
+		}
+		if len(found) == 2 {
+			links = append(links, strongLink{h, found[0], found[1]})
+		}
+	}
+	return links
+}
+
+// Two rows with a strong link each, with one end of each in the same column.
+// Whichever row doesn't have the value at that end has it at the other, so
+// it can go from the cells seeing both of the other ends (and vice/versa for
+// columns).
+func skyscraper(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return turbotFish(sud, step, "Skyscraper")
+}
+
+// A strong link in a row and one in a column, with an end of each in the
+// same box. Works the same way as a skyscraper.
+func twoStringKite(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return turbotFish(sud, step, "2-String Kite")
+}
+
+// Any other pair of strong links with an end of one seeing an end of the
+// other, such as ones in boxes or diagonals.
+func turbot(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return turbotFish(sud, step, "Turbot Fish")
+}
+
+// Looks for two strong links a1=a2 and b1=b2 for a value where a2 sees b1.
+// If a1 isn't the value a2 is, so b1 isn't, so b2 is: one of a1 or b2 has
+// the value, and any cell seeing both can't. Only the chains whose shape
+// turbotShape names `technique` are reported.
+func turbotFish(sud *SudokuSquare, step StepFunc, technique string) (bool, error) {
+	n := sud.size()
+	geo := sud.geo
+	changes := false
+	for val := 1; val <= n; val++ {
+		links := strongLinks(sud, val)
+		for i, l1 := range links {
+			for _, l2 := range links[i+1:] {
+				for _, a := range [][2]int{{l1.a, l1.b}, {l1.b, l1.a}} {
+					for _, b := range [][2]int{{l2.a, l2.b}, {l2.b, l2.a}} {
+						a1, a2, b1, b2 := a[0], a[1], b[0], b[1]
+						if a1 == b1 || a1 == b2 || a2 == b1 || a2 == b2 || !geo.sees(a2, b1) {
+							continue
+						}
+						if turbotShape(geo, l1, l2, a2, b1) != technique {
+							continue
+						}
+						st := Step{Technique: technique, House: geo.houses[l1.house].name + " & " + geo.houses[l2.house].name}
+						for _, idx := range []int{a1, a2, b1, b2} {
+							st.addCell(sud.cellAt(idx))
+						}
+						for _, idx := range geo.peers[a1] {
+							if idx != a2 && idx != b1 && idx != b2 && geo.sees(idx, b2) {
+								st.eliminate(sud.cellAt(idx), val)
+							}
+						}
+						if len(st.Eliminations) > 0 {
+							changes = true
+							if e := report(step, st); e != nil {
+								return true, e
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Names the chain l1 - l2, joined where a2 sees b1.
+func turbotShape(geo *geometry, l1, l2 strongLink, a2, b1 int) string {
+	n := geo.size
+	k1, k2 := geo.houseKind(l1.house), geo.houseKind(l2.house)
+	switch {
+	case k1 == rowHouse && k2 == rowHouse && a2%n == b1%n,
+		k1 == columnHouse && k2 == columnHouse && a2/n == b1/n:
+		return "Skyscraper"
+	case (k1 == rowHouse && k2 == columnHouse || k1 == columnHouse && k2 == rowHouse) &&
+		geo.cellBlock[a2] == geo.cellBlock[b1]:
+		return "2-String Kite"
+	}
+	return "Turbot Fish"
+}
+
+// A box whose candidates for a value all lie on one row and one column of
+// it (but not just one of them). Take a strong link in another row, with
+// one end in the box's column: if that end has the value, the box's has to
+// be on its row, otherwise the link's other end has it. Either way the
+// value can't be where the box's row meets the link's other column. The same
+// goes for strong links in columns. Boxes are regions in jigsaw sudoku.
+func emptyRectangle(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
+	geo := sud.geo
+	changes := false
+	for val := 1; val <= n; val++ {
+		links := strongLinks(sud, val)
+		for b, h := range geo.blocks {
+			var inBlock []int
+			for _, idx := range geo.houses[h].cells {
+				if sud.cellAt(idx).hasCandidate(val) {
+					inBlock = append(inBlock, idx)
+				}
+			}
+			if len(inBlock) < 2 {
+				continue
+			}
+			for row := 0; row < n; row++ {
+				for col := 0; col < n; col++ {
+					if !emptyRectangleFits(inBlock, n, row, col) {
+						continue
+					}
+					for _, l := range links {
+						target, ends, ok := emptyRectangleTarget(geo, l, b, row, col)
+						if !ok {
+							continue
+						}
+						st := Step{Technique: "Empty Rectangle", House: geo.houses[h].name}
+						for _, idx := range inBlock {
+							st.addCell(sud.cellAt(idx))
+						}
+						st.addCell(sud.cellAt(ends[0]))
+						st.addCell(sud.cellAt(ends[1]))
+						if !st.eliminate(sud.cellAt(target), val) {
+							continue
+						}
+						changes = true
+						if e := report(step, st); e != nil {
+							return true, e
+						}
+					}
+				}
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Whether the box's candidates are all on row or col, with some off each.
+func emptyRectangleFits(inBlock []int, n, row, col int) bool {
+	offRow, offCol := false, false
+	for _, idx := range inBlock {
+		r, c := idx/n, idx%n
+		if r != row && c != col {
+			return false
+		}
+		offRow = offRow || r != row
+		offCol = offCol || c != col
+	}
+	return offRow && offCol
+}
+
+// The cell an empty rectangle in box b on row and col removes the value
+// from with strong link l, if l fits it, along with l's ends.
+func emptyRectangleTarget(geo *geometry, l strongLink, b, row, col int) (int, [2]int, bool) {
+	n := geo.size
+	kind := geo.houseKind(l.house)
+	for _, ends := range [][2]int{{l.a, l.b}, {l.b, l.a}} {
+		near, far := ends[0], ends[1]
+		if geo.cellBlock[near] == b || geo.cellBlock[far] == b {
+			continue
+		}
+		var target int
+		switch {
+		case kind == rowHouse && near/n != row && near%n == col:
+			target = row*n + far%n
+		case kind == columnHouse && near%n != col && near/n == row:
+			target = (far/n)*n + col
+		default:
+			continue
+		}
+		if geo.cellBlock[target] != b {
+			return target, ends, true
+		}
+	}
+	return 0, [2]int{}, false
+}
@@ -0,0 +1,112 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Removes val from the cells of a house except those in keep.
+func keepOnly(s *SudokuSquare, val int, house []CellRef, keep ...CellRef) {
+	for _, ref := range house {
+		if !containsRef(keep, ref) {
+			s.cells[ref.Row][ref.Col].removeCandidate(val)
+		}
+	}
+}
+
+func rowRefs(row int) []CellRef {
+	var refs []CellRef
+	for col := 0; col < 9; col++ {
+		refs = append(refs, CellRef{row, col})
+	}
+	return refs
+}
+
+func colRefs(col int) []CellRef {
+	var refs []CellRef
+	for row := 0; row < 9; row++ {
+		refs = append(refs, CellRef{row, col})
+	}
+	return refs
+}
+
+func collectSteps(t *testing.T, fn sudokuAlgo, s *SudokuSquare) []Step {
+	var steps []Step
+	_, err := fn(s, func(st Step) bool {
+		steps = append(steps, st)
+		return true
+	})
+	assert.NoError(t, err)
+	return steps
+}
+
+func TestSkyscraper(t *testing.T) {
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	keepOnly(s, 1, rowRefs(4), CellRef{4, 0}, CellRef{4, 5})
+	noOpCheck(t, twoStringKite, s)
+	noOpCheck(t, turbot, s)
+
+	assert.Equal(t, []Step{{
+		Technique:    "Skyscraper",
+		House:        "row 0 & row 4",
+		Cells:        []CellRef{{0, 4}, {0, 0}, {4, 0}, {4, 5}},
+		Eliminations: []Candidate{{1, 5, 1}, {2, 5, 1}, {3, 4, 1}, {5, 4, 1}},
+	}}, collectSteps(t, skyscraper, s))
+	noOpCheck(t, skyscraper, s)
+}
+
+func TestTwoStringKite(t *testing.T) {
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 1}, CellRef{0, 6})
+	keepOnly(s, 1, colRefs(0), CellRef{2, 0}, CellRef{7, 0})
+	noOpCheck(t, skyscraper, s)
+
+	assert.Equal(t, []Step{{
+		Technique:    "2-String Kite",
+		House:        "row 0 & column 0",
+		Cells:        []CellRef{{0, 6}, {0, 1}, {2, 0}, {7, 0}},
+		Eliminations: []Candidate{{7, 6, 1}},
+	}}, collectSteps(t, twoStringKite, s))
+	noOpCheck(t, twoStringKite, s)
+}
+
+func TestTurbotFish(t *testing.T) {
+	// a strong link in a row joined to one in a box
+	s := newEmptySudoku()
+	keepOnly(s, 1, rowRefs(0), CellRef{0, 0}, CellRef{0, 4})
+	box := []CellRef{{3, 3}, {3, 4}, {3, 5}, {4, 3}, {4, 4}, {4, 5}, {5, 3}, {5, 4}, {5, 5}}
+	keepOnly(s, 1, box, CellRef{3, 4}, CellRef{5, 5})
+	noOpCheck(t, skyscraper, s)
+	noOpCheck(t, twoStringKite, s)
+
+	assert.Equal(t, []Step{{
+		Technique:    "Turbot Fish",
+		House:        "row 0 & block 1 1",
+		Cells:        []CellRef{{0, 0}, {0, 4}, {3, 4}, {5, 5}},
+		Eliminations: []Candidate{{5, 0, 1}},
+	}}, collectSteps(t, turbot, s))
+	noOpCheck(t, turbot, s)
+}
+
+func TestEmptyRectangle(t *testing.T) {
+	s := newEmptySudoku()
+	box := []CellRef{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}
+	keepOnly(s, 1, box, CellRef{0, 0}, CellRef{0, 1}, CellRef{1, 0})
+	keepOnly(s, 1, rowRefs(5), CellRef{5, 0}, CellRef{5, 6})
+
+	assert.Equal(t, []Step{{
+		Technique:    "Empty Rectangle",
+		House:        "block 0 0",
+		Cells:        []CellRef{{0, 0}, {0, 1}, {1, 0}, {5, 0}, {5, 6}},
+		Eliminations: []Candidate{{0, 6, 1}},
+	}}, collectSteps(t, emptyRectangle, s))
+	noOpCheck(t, emptyRectangle, s)
+
+	t.Run("not when the box only has the value on one line", func(t *testing.T) {
+		s := newEmptySudoku()
+		keepOnly(s, 1, box, CellRef{0, 0}, CellRef{0, 1})
+		keepOnly(s, 1, rowRefs(5), CellRef{5, 0}, CellRef{5, 6})
+		noOpCheck(t, emptyRectangle, s)
+	})
+}
@@ -1,115 +1,303 @@
 package sodacouplib
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"math/bits"
 	"strings"
-	"unicode"
 )
 
 // SudokuSquare Wraps the square in some useful constructs.
 type SudokuSquare struct {
-	cells [9][9]SudokuCell
-	nines *[3 * 9]nonagon // lazy created, see description of nonagon struct below
+	geo   *geometry
+	cells [][]SudokuCell
+	nines []nonagon // lazy created, see description of nonagon struct below
 }
 
 // SudokuCell adds a little info to each cell to make heuristic algorithms easier.
 // Keeps a track of what are valid candidates for the cell in the candidates bitmask.
-// To keep that consistent all updates have to be done through SudokuSquare.setCell.
+// To keep that consistent all updates have to be done through SudokuSquare.setCell
+// (or Set, Clear and EliminateCandidate from outside the package).
 type SudokuCell struct {
 	row, col   int
-	value      byte // 0 -> 9 inclusive (0 for unset)
+	value      byte // 0 -> size inclusive (0 for unset)
 	isSet      bool
-	candidates uint16 // bitmask 2^1 -> 2^9 of still valid cell numbers
+	candidates uint32 // bitmask 2^1 -> 2^size of still valid cell numbers
 }
 
-// A nonagon is a generic way to access the 9 cells of a row, column or block.
+// A nonagon is a generic way to access the cells of a row, column or block,
+// or any other house a variant adds (nine of them on a classic board, hence
+// the name).
 // Some heuristic algorithms behave the same for each of those three, so
 // by putting in a layer of indirection those algorithms don't have to be
 // written three times.
 type nonagon struct {
 	name  string
-	cells [9]*SudokuCell // pointers to the cells that make up this row/column/block.
+	cells []*SudokuCell // pointers to the cells that make up this row/column/block.
 }
 
 // NewSudokuSquare Create a SudokuSquare struct given a string that
 // roughly looks like a sudoku problem. Can have many spaces/newlines, but
-// just needs '_' for empty cells or a number 1-9 for filled cells.
+// just needs '_' for empty cells or a number 1-9 for filled cells. Boards
+// other than 9×9 are picked up from the number of cells, with the box shape
+// from LayoutForSize; bigger ones use letters for values above 9.
 func NewSudokuSquare(stringRepresentation string) (*SudokuSquare, error) {
-	stringRepresentation = filterValidChars(stringRepresentation)
-	if len(stringRepresentation) != 81 {
-		return nil, errors.New("doesn't look like a valid sudoku")
+	layout, ok := layoutOf(stringRepresentation)
+	if !ok {
+		return nil, &ParseError{msg: "doesn't look like a valid sudoku"}
 	}
+	return parseSudoku(stringRepresentation, newGeometry(layout, Variant{}))
+}
 
-	sud := newEmptySudoku()
+// NewSudokuSquareWithLayout is NewSudokuSquare for a board of a given shape.
+func NewSudokuSquareWithLayout(stringRepresentation string, layout Layout) (*SudokuSquare, error) {
+	return NewSudokuSquareWithVariant(stringRepresentation, layout, Variant{})
+}
 
-	si := 0
-	for i := 0; i < 9; i++ {
-		for j := 0; j < 9; j++ {
-			r := stringRepresentation[si]
-			if r != '_' {
-				if e := sud.setCell(i, j, int(r-'0')); e != nil {
-					return nil, e
-				}
+// NewSudokuSquareWithVariant is NewSudokuSquareWithLayout with extra rules,
+// such as killer cages.
+func NewSudokuSquareWithVariant(stringRepresentation string, layout Layout, variant Variant) (*SudokuSquare, error) {
+	if err := layout.validate(); err != nil {
+		return nil, err
+	}
+	if err := variant.validate(layout); err != nil {
+		return nil, err
+	}
+	if len(filterValidChars(stringRepresentation, layout.Size())) != layout.Size()*layout.Size() {
+		return nil, &ParseError{msg: "doesn't look like a valid sudoku"}
+	}
+	return parseSudoku(stringRepresentation, newGeometry(layout, variant))
+}
+
+// Reads the cells from the input, which has already been checked to have
+// the right number of them. A symbol that isn't a value on the board is a
+// *ParseError saying where it is, a value given twice in a house a
+// *CellError.
+func parseSudoku(input string, geo *geometry) (*SudokuSquare, error) {
+	sud := newEmptySudokuWithGeometry(geo)
+	n := sud.size()
+
+	idx := 0
+	for offset := 0; offset < len(input); offset++ {
+		r := input[offset]
+		if !isCellSymbol(rune(r), n) {
+			continue
+		}
+		if r != '_' {
+			val := symbolValue(r)
+			if val < 1 || val > n {
+				return nil, parseErrorAt(input, offset, "%q isn't a value on a %dx%d board", r, n, n)
+			}
+			if e := duplicateClue(sud, idx, val); e != nil {
+				return nil, e
+			}
+			if e := sud.setCell(idx/n, idx%n, val); e != nil {
+				return nil, e
 			}
-			si++
 		}
+		idx++
 	}
 	return sud, nil
 }
 
+// Copy of the square that shares nothing with the original. The nonagons
+// point into the original's cells, so they're left for the copy to make
+// afresh.
+func (sud *SudokuSquare) clone() *SudokuSquare {
+	c := &SudokuSquare{geo: sud.geo}
+	c.cells = makeCells(sud.size())
+	for r := range sud.cells {
+		copy(c.cells[r], sud.cells[r])
+	}
+	return c
+}
+
 // An empty sudoku is probably an oxymoron, but it's useful for testing.
 func newEmptySudoku() *SudokuSquare {
-	var sud SudokuSquare
-	for r := 0; r < 9; r++ {
-		for c := 0; c < 9; c++ {
-			sud.cells[r][c].init(r, c)
+	return newEmptySudokuWithLayout(ClassicLayout)
+}
+
+func newEmptySudokuWithLayout(layout Layout) *SudokuSquare {
+	return newEmptySudokuWithGeometry(newGeometry(layout, Variant{}))
+}
+
+func newEmptySudokuWithGeometry(geo *geometry) *SudokuSquare {
+	sud := SudokuSquare{geo: geo}
+	n := sud.size()
+	sud.cells = makeCells(n)
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			sud.cells[r][c].init(r, c, n)
 		}
 	}
 	return &sud
 }
 
-// Solve does the magic.
-func (sud *SudokuSquare) Solve() error {
-	solved, e := trySolveWithHeuristics(sud)
+// Rows of cells all backed by the one array.
+func makeCells(n int) [][]SudokuCell {
+	all := make([]SudokuCell, n*n)
+	cells := make([][]SudokuCell, n)
+	for r := range cells {
+		cells[r] = all[r*n : (r+1)*n]
+	}
+	return cells
+}
+
+// Cell at row*size+col, the way the geometry refers to cells.
+func (sud *SudokuSquare) cellAt(idx int) *SudokuCell {
+	n := sud.size()
+	return &sud.cells[idx/n][idx%n]
+}
+
+// Number of cells along each side of the square.
+func (sud *SudokuSquare) size() int {
+	return sud.geo.size
+}
+
+// SolveOptions picks how Solve goes about solving. The zero value solves
+// with DefaultStrategies.
+type SolveOptions struct {
+	// Strategies the heuristic solver works through, in order.
+	Strategies *StrategySet
+	// AssumeUnique switches on the strategies in the set that are only
+	// sound if the puzzle has exactly one solution, such as Unique
+	// Rectangle. Only set it when that's known to be so.
+	AssumeUnique bool
+	// Backend finishes off whatever the strategies can't, Backtracking if
+	// not set.
+	Backend Backend
+	// MaxNodes is the most nodes (strategies tried and guesses made) solving
+	// or generating may take before giving up with a *BudgetError. 0 for no
+	// limit.
+	MaxNodes int
+}
+
+// A Backend is a way of searching out a solution, for Solve to fall back on.
+type Backend int
+
+const (
+	// Backtracking guesses at whichever cell has fewest values left, and
+	// follows each guess through with singles before making the next.
+	Backtracking Backend = iota
+	// DancingLinks treats the square as an exact cover problem and solves
+	// it with Knuth's Algorithm X, which is a lot quicker on puzzles with
+	// few givens.
+	DancingLinks
+)
+
+func (b Backend) solver() func(*SudokuSquare, *budget) (bool, error) {
+	if b == DancingLinks {
+		return dancingLinks
+	}
+	return fastBackTrack
+}
+
+func (opts SolveOptions) strategies() *StrategySet {
+	set := opts.Strategies
+	if set == nil {
+		set = DefaultStrategies()
+	}
+	if opts.AssumeUnique {
+		set = set.clone()
+		for i, e := range set.entries {
+			if uniquenessStrategies[e.strategy.Name()] {
+				set.entries[i].enabled = true
+			}
+		}
+	}
+	return set
+}
+
+// Solve does the magic. Returns the steps taken to get to the solution.
+func (sud *SudokuSquare) Solve(opts SolveOptions) ([]Step, error) {
+	return sud.SolveContext(context.Background(), opts)
+}
+
+// SolveContext is Solve, giving up with a *BudgetError once ctx is done or
+// opts.MaxNodes have been used. The square keeps whatever the strategies
+// had worked out by then, and the steps taken to get there are returned.
+// ctx is checked before each strategy is tried and each guess made, not
+// while a strategy runs, so a slow one (a forcing chain, say) can take it
+// past its deadline.
+func (sud *SudokuSquare) SolveContext(ctx context.Context, opts SolveOptions) ([]Step, error) {
+	var steps []Step
+	b := newBudget(ctx, opts.MaxNodes)
+	solved, e := trySolveWithHeuristics(sud, opts.strategies(), func(st Step) bool {
+		steps = append(steps, st)
+		return true
+	}, b)
 	if e != nil {
-		return e
+		return steps, e
 	} else if solved {
-		return nil
+		return steps, nil
 	}
 
 	log.Println("Unsolved by heuristics. Applying backtracking.")
-	_, e = backTrack(sud)
-	return e
+	st, e := backTrackStep(sud, opts.Backend.solver(), b)
+	if e != nil {
+		return steps, e
+	}
+	return append(steps, st), nil
 }
 
-func trySolveWithHeuristics(sud *SudokuSquare) (bool, error) {
-	heuristicAlgorithms := []sudokuAlgo{
-		sanityCheck,
-		nakedSingle,
-		hiddenSingle,
-		pointingPair,
-		claimingPair,
-		nakedPair,
-		hiddenPair,
+// NextStep applies the first deduction the strategies can find, trying them
+// in order, and returns it. Returns nil if none of them can make progress.
+func (sud *SudokuSquare) NextStep(opts SolveOptions) (*Step, error) {
+	if _, err := sanityCheck(sud); err != nil {
+		return nil, err
+	}
+	var found *Step
+	for _, s := range opts.strategies().Enabled() {
+		_, err := s.Apply(sud, func(st Step) bool {
+			found = &st
+			return false
+		})
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			return found, nil
+		}
 	}
+	return nil, nil
+}
+
+// PeekStep is NextStep without changing the square, for giving hints.
+func (sud *SudokuSquare) PeekStep(opts SolveOptions) (*Step, error) {
+	return sud.clone().NextStep(opts)
+}
+
+// Applies the strategies until they stop making progress, each one tried
+// being a node of b. Like NextStep it goes back to the first strategy after
+// any progress, so the expensive ones at the end only run once the cheap
+// ones are stuck. Reports whether that solved the square.
+func trySolveWithHeuristics(sud *SudokuSquare, strategies *StrategySet, step StepFunc, b *budget) (bool, error) {
+	heuristicAlgorithms := strategies.Enabled()
 
 	e := untilTrue(func() (bool, error) {
-		changesMade := false
-		for _, fn := range heuristicAlgorithms {
-			impacting, err := fn(sud)
+		if _, err := sanityCheck(sud); err != nil {
+			return false, err
+		}
+		if isSolved(sud) {
+			return true, nil
+		}
+		for _, s := range heuristicAlgorithms {
+			if err := b.spend(); err != nil {
+				return false, err
+			}
+			impacting, err := s.Apply(sud, step)
 			if err != nil {
 				return false, err
 			}
 			if impacting {
-				log.Println("...done applying:", getFunctionName(fn))
+				log.Println("...done applying:", s.Name())
 				log.Println(sud.asTableStringWithCandidates())
+				return false, nil
 			}
-			changesMade = changesMade || impacting
 		}
-		return !changesMade, nil
+		return true, nil
 	})
 	return isSolved(sud), e
 }
@@ -125,47 +313,81 @@ func (c SudokuCell) String() string {
 	return fmt.Sprintf("[%d,%d ? %s]", c.row, c.col, c.candidateString())
 }
 
-func filterValidChars(s string) string {
+func filterValidChars(s string, size int) string {
 	return strings.Map(func(r rune) rune {
-		if r == '_' || unicode.IsNumber(r) {
+		if isCellSymbol(r, size) {
 			return r
 		}
 		return -1
 	}, s)
 }
 
+// Whether r stands for a cell when reading a square of the given size,
+// rather than being spacing between them. Letters are only values on boards
+// bigger than 9x9, so on the rest they can label the square without being
+// taken for cells.
+func isCellSymbol(r rune, size int) bool {
+	if r == '_' || (r >= '0' && r <= '9') {
+		return true
+	}
+	return size > ClassicLayout.Size() && ((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'))
+}
+
+// The layout of the square written out in s, going by how many cells it
+// has. Digits and '_' are counted first, for boards up to 9x9, and only if
+// they don't make one are letters counted as well.
+func layoutOf(s string) (Layout, bool) {
+	classic := ClassicLayout.Size()
+	if n := isqrt(len(filterValidChars(s, classic))); n <= classic {
+		if layout, err := LayoutForSize(n); err == nil {
+			return layout, true
+		}
+	}
+	n := isqrt(len(filterValidChars(s, maxSize)))
+	layout, err := LayoutForSize(n)
+	return layout, err == nil && n > classic
+}
+
+// Integer square root, or -1 if n isn't a perfect square.
+func isqrt(n int) int {
+	for r := 0; r*r <= n; r++ {
+		if r*r == n {
+			return r
+		}
+	}
+	return -1
+}
+
 // FormatSudoku takes a sudoku like string and prints it in the square format
 // with spaces/empty line between blocks.
 func FormatSudoku(s string) (string, error) {
-	s = filterValidChars(s)
-	if len(s) != 81 {
-		return "", errors.New("invalid length")
-	}
-
-	var o []byte = make([]byte, 81+9+2+9*2) // numbers + newlines + extra newlines + spaces between squares
-	t := 0
-	for i := 0; i < 81; i++ {
-		o[t] = s[i]
-		t++
-		if i%27 == 26 && i != 80 {
-			o[t] = '\n'
-			t++
-		}
-		if i%9 == 8 {
-			o[t] = '\n'
-			t++
-		} else if i%3 == 2 {
-			o[t] = ' '
-			t++
+	layout, ok := layoutOf(s)
+	if !ok {
+		return "", &ParseError{msg: "invalid length"}
+	}
+	s = filterValidChars(s, layout.Size())
+
+	n, br, bc := layout.Size(), layout.BoxRows, layout.BoxCols
+	var o strings.Builder
+	for i := 0; i < n*n; i++ {
+		o.WriteByte(s[i])
+		row, col := i/n, i%n
+		if col == n-1 {
+			o.WriteByte('\n')
+			if row%br == br-1 && row != n-1 {
+				o.WriteByte('\n')
+			}
+		} else if col%bc == bc-1 {
+			o.WriteByte(' ')
 		}
 	}
-	return string(o), nil
+	return o.String(), nil
 }
 
 func (sud *SudokuSquare) SetCount() int {
 	cnt := 0
-	for r := 0; r < 9; r++ {
-		for c := 0; c < 9; c++ {
+	for r := range sud.cells {
+		for c := range sud.cells[r] {
 			if sud.cells[r][c].isSet {
 				cnt++
 			}
@@ -176,25 +398,27 @@ func (sud *SudokuSquare) SetCount() int {
 
 // Format a sudoku as a table with lines between blocks.
 func (sud SudokuSquare) asTableString() string {
+	n := sud.size()
+	boxRows, boxCols := sud.geo.drawnBox()
 	var sb strings.Builder
-	const hr = " -------------------------\n"
+	hr := " " + strings.Repeat("-", 2*n+2*n/boxCols+1) + "\n"
 	sb.WriteString(hr)
-	for r := 0; r < 9; r++ {
+	for r := 0; r < n; r++ {
 		sb.WriteString(" |")
-		for c := 0; c < 9; c++ {
+		for c := 0; c < n; c++ {
 			sb.WriteByte(' ')
 			cell := sud.cells[r][c]
 			if cell.isSet {
-				fmt.Fprintf(&sb, "%d", cell.value)
+				sb.WriteByte(valueSymbol(int(cell.value)))
 			} else {
 				sb.WriteByte('_')
 			}
-			if c%3 == 2 {
+			if c%boxCols == boxCols-1 {
 				sb.WriteString(" |")
 			}
 		}
 		sb.WriteByte('\n')
-		if r%3 == 2 {
+		if r%boxRows == boxRows-1 {
 			sb.WriteString(hr)
 		}
 	}
@@ -202,14 +426,17 @@ func (sud SudokuSquare) asTableString() string {
 }
 
 func (sud SudokuSquare) asTableStringWithCandidates() string {
-	numFormat, strFormat := "%d", "%s"
-	hr := strings.Repeat("-", 2*9+7) + "\n"
+	n := sud.size()
+	boxRows, boxCols := sud.geo.drawnBox()
+	boxes := n / boxCols
+	strFormat := "%s"
+	hr := strings.Repeat("-", 2*n+2*boxes+1) + "\n"
 	maxCandidates := 0
-	for r := 0; r < 9; r++ {
-		for c := 0; c < 9; c++ {
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
 			cell := sud.cells[r][c]
 			if !cell.isSet {
-				c := bits.OnesCount16(cell.candidates)
+				c := bits.OnesCount32(cell.candidates)
 				if c > maxCandidates {
 					maxCandidates = c
 				}
@@ -217,40 +444,39 @@ func (sud SudokuSquare) asTableStringWithCandidates() string {
 		}
 	}
 	if maxCandidates > 0 {
-		numFormat = fmt.Sprintf("%%%dd", maxCandidates+2)
 		strFormat = fmt.Sprintf("%%%ds", maxCandidates+2)
-		hr = strings.Repeat("-", (maxCandidates+3)*9+7) + "\n"
+		hr = strings.Repeat("-", (maxCandidates+3)*n+2*boxes+1) + "\n"
 	}
 	var sb strings.Builder
 	sb.WriteByte('\n')
 	sb.WriteString(hr)
-	for r := 0; r < 9; r++ {
+	for r := 0; r < n; r++ {
 		sb.WriteByte('|')
-		for c := 0; c < 9; c++ {
+		for c := 0; c < n; c++ {
 			cell := sud.cells[r][c]
 			sb.WriteByte(' ')
 			if cell.isSet {
-				fmt.Fprintf(&sb, numFormat, cell.value)
+				fmt.Fprintf(&sb, strFormat, string(valueSymbol(int(cell.value))))
 			} else {
 				fmt.Fprintf(&sb, strFormat, cell.candidateString())
 			}
-			if c%3 == 2 {
+			if c%boxCols == boxCols-1 {
 				sb.WriteString(" |")
 			}
 		}
 		sb.WriteByte('\n')
-		if r%3 == 2 {
+		if r%boxRows == boxRows-1 {
 			sb.WriteString(hr)
 		}
 	}
 	return sb.String()
 }
 
-func maskToString(mask uint16) string {
+func maskToString(mask uint32) string {
 	var sb strings.Builder
-	for n := 1; n <= 9; n++ {
+	for n := 1; n <= maxSize; n++ {
 		if (1<<n)&mask > 0 {
-			fmt.Fprintf(&sb, "%d", n)
+			sb.WriteByte(valueSymbol(n))
 		}
 	}
 	return sb.String()
@@ -261,34 +487,39 @@ func (c SudokuCell) candidateString() string {
 }
 
 func (c *SudokuCell) removeCandidate(val int) {
-	if val < 1 || val > 9 {
+	if val < 1 || val > maxSize {
 		panic("invalid")
 	}
-	var msk uint16 = 1 << val
+	var msk uint32 = 1 << val
 	c.candidates = c.candidates &^ msk
 }
 
 func (c SudokuCell) hasCandidate(val int) bool {
-	if val < 1 || val > 9 {
+	if val < 1 || val > maxSize {
 		panic("invalid")
 	}
-	var msk uint16 = 1 << val
+	var msk uint32 = 1 << val
 	return !c.isSet && (c.candidates&msk == msk)
 }
 
-func (c *SudokuCell) init(row, col int) {
+func (c *SudokuCell) init(row, col, size int) {
 	c.isSet = false
 	c.value = 0
-	c.candidates = 0b1111111110
+	c.candidates = allCandidates(size)
 	c.row = row
 	c.col = col
 }
 
+// Candidate mask with every value 1 -> size in it.
+func allCandidates(size int) uint32 {
+	return (1<<(size+1) - 1) &^ 1
+}
+
 func applyToCells(sud *SudokuSquare, fn func(cell *SudokuCell) (bool, error)) (bool, error) {
 	result := false
 
-	for row := 0; row < 9; row++ {
-		for col := 0; col < 9; col++ {
+	for row := range sud.cells {
+		for col := range sud.cells[row] {
 			b, e := fn(&sud.cells[row][col])
 			if e != nil {
 				return false, e
@@ -301,46 +532,38 @@ func applyToCells(sud *SudokuSquare, fn func(cell *SudokuCell) (bool, error)) (b
 }
 
 func (sud *SudokuSquare) setCell(row int, col int, val int) error {
-	if row < 0 || row >= 9 || col < 0 || col >= 9 {
-		return errors.New("invalid")
-	}
-	if val < 1 || val > 9 {
-		return errors.New("invalid")
+	if err := sud.checkValue(row, col, val); err != nil {
+		return err
 	}
+	n := sud.size()
 	c := &sud.cells[row][col]
 	if c.isSet {
-		return errors.New("trying to update already set cell")
+		return cellError(ErrContradiction, row, col, val, "", "trying to update already set cell %d,%d to %d", row, col, val)
 	}
 	if !c.hasCandidate(val) {
-		return fmt.Errorf("trying to add %d to %d,%d", val, row, col)
+		return cellError(ErrContradiction, row, col, val, "", "trying to add %d to %d,%d", val, row, col)
 	}
 	c.isSet = true
 	c.value = byte(val)
 
-	/* update rows */
-	for i := 0; i < 9; i++ {
-		sud.cells[i][col].removeCandidate(val)
-	}
-
-	/* update cols */
-	for j := 0; j < 9; j++ {
-		sud.cells[row][j].removeCandidate(val)
+	/* update rows, cols, squares */
+	for _, h := range sud.geo.cellHouses[row*n+col] {
+		for _, idx := range sud.geo.houses[h].cells {
+			sud.cells[idx/n][idx%n].removeCandidate(val)
+		}
 	}
-
-	/* update squares */
-	si := row - row%3
-	sj := col - col%3
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			sud.cells[si+i][sj+j].removeCandidate(val)
+	/* and the cage, which can't repeat a value either */
+	if c := sud.geo.cellCage[row*n+col]; c >= 0 {
+		for _, idx := range sud.geo.cages[c].cells {
+			sud.cells[idx/n][idx%n].removeCandidate(val)
 		}
 	}
 	return nil
 }
 
 func isSolved(sud *SudokuSquare) bool {
-	for r := 0; r < 9; r++ {
-		for c := 0; c < 9; c++ {
+	for r := range sud.cells {
+		for c := range sud.cells[r] {
 			if !sud.cells[r][c].isSet {
 				return false
 			}
@@ -352,14 +575,17 @@ func isSolved(sud *SudokuSquare) bool {
 // Both ensures the problem is a valid sudoku and that the SudokuSquare doesn't
 // get into an invalid state by programming bugs.
 func sanityCheck(sud *SudokuSquare) (bool, error) {
+	n := sud.size()
 	// check individual cells are correct
 	_, err := applyToCells(sud, func(cell *SudokuCell) (bool, error) {
 		if cell.isSet {
-			if !(cell.value >= 1 && cell.value <= 9) {
-				return false, fmt.Errorf("cell %d,%d marked set but no value found", cell.row, cell.col)
+			if !(cell.value >= 1 && int(cell.value) <= n) {
+				return false, cellError(ErrOutOfRange, cell.row, cell.col, int(cell.value), "",
+					"cell %d,%d marked set but no value found", cell.row, cell.col)
 			}
 		} else if !(cell.candidates > 0) {
-			return false, fmt.Errorf("cell %d,%d marked unset but no candidates available", cell.row, cell.col)
+			return false, cellError(ErrContradiction, cell.row, cell.col, 0, "",
+				"cell %d,%d marked unset but no candidates available", cell.row, cell.col)
 		}
 		return false, nil
 	})
@@ -368,15 +594,15 @@ func sanityCheck(sud *SudokuSquare) (bool, error) {
 	}
 
 	// check each row/column/block are correct
-	return applyToNonagons(sud, func(niner nonagon) (bool, error) {
-		var setValues [10]int
-		var availableValues [10]int
+	_, err = applyToNonagons(sud, func(niner nonagon) (bool, error) {
+		setValues := make([]int, n+1)
+		availableValues := make([]int, n+1)
 
 		for _, cell := range niner.cells {
 			if cell.isSet {
 				setValues[cell.value] = setValues[cell.value] + 1
 			} else {
-				for val := 1; val <= 9; val++ {
+				for val := 1; val <= n; val++ {
 					if cell.hasCandidate(val) {
 						availableValues[val] = availableValues[val] + 1
 					}
@@ -384,16 +610,21 @@ func sanityCheck(sud *SudokuSquare) (bool, error) {
 
 			}
 		}
-		for val := 1; val <= 9; val++ {
+		for val := 1; val <= n; val++ {
 			if setValues[val] > 1 {
-				return false, fmt.Errorf("%s two values set for %d", niner.name, val)
+				return false, houseError(ErrContradiction, niner.name, val, "%s two values set for %d", niner.name, val)
 			} else if setValues[val] != 1 && availableValues[val] == 0 {
-				return false, fmt.Errorf("%s no candidates available for %d", niner.name, val)
+				return false, houseError(ErrContradiction, niner.name, val, "%s no candidates available for %d", niner.name, val)
 			}
 		}
 
 		return false, nil
 	})
+	if err != nil {
+		return false, err
+	}
+
+	return false, checkCages(sud)
 }
 
 type nonagonFunction func(nonagon) (bool, error)
@@ -415,68 +646,17 @@ func applyToNonagons(sud *SudokuSquare, fns ...nonagonFunction) (bool, error) {
 	return result, nil
 }
 
-type blockFunction func(blkRowStart, blkRowEnd, blkColStart, blkColEnd int) (bool, error)
-
-func applyToBlocks(sud *SudokuSquare, fn blockFunction) (bool, error) {
-	result := false
-	for blkRowStart := 0; blkRowStart < 9; blkRowStart += 3 {
-		for blkColStart := 0; blkColStart < 9; blkColStart += 3 {
-			blkRowEnd, blkColEnd := blkRowStart+2, blkColStart+2
-			b, e := fn(blkRowStart, blkRowEnd, blkColStart, blkColEnd)
-			if e != nil {
-				return false, e
-			}
-			result = result || b
-		}
-	}
-	return result, nil
-}
-
 func (sud *SudokuSquare) createNonagons() {
-	var n [3 * 9]nonagon
-
-	s := 0
-
-	// rows
-	for row := 0; row < 9; row++ {
-		var rows [9]*SudokuCell
-		for col := 0; col < 9; col++ {
-			rows[col] = &sud.cells[row][col]
-		}
-		name := fmt.Sprintf("   row  %d", row)
-		n[s] = nonagon{name, rows}
-		s++
-	}
-
-	// cols
-	for col := 0; col < 9; col++ {
-		var cols [9]*SudokuCell
-		for row := 0; row < 9; row++ {
-			cols[row] = &sud.cells[row][col]
-		}
-		name := fmt.Sprintf(" column %d", col)
-		n[s] = nonagon{name, cols}
-		s++
-	}
-
-	// squares
-	naming := func(a int) int { return (a / 3) }
-	for si := 0; si < 9; si += 3 {
-		for sj := 0; sj < 9; sj += 3 {
-			var cells [9]*SudokuCell
-			idx := 0
-			for row := si; row < si+3; row++ {
-				for col := sj; col < sj+3; col++ {
-					cells[idx] = &sud.cells[row][col]
-					idx++
-				}
-			}
-			name := fmt.Sprintf("block %d %d", naming(si), naming(sj))
-			n[s] = nonagon{name, cells}
-			s++
+	n := sud.size()
+	nines := make([]nonagon, len(sud.geo.houses))
+	for i, h := range sud.geo.houses {
+		cells := make([]*SudokuCell, len(h.cells))
+		for j, idx := range h.cells {
+			cells[j] = &sud.cells[idx/n][idx%n]
 		}
+		nines[i] = nonagon{h.name, cells}
 	}
-	sud.nines = &n
+	sud.nines = nines
 }
 
 // Run until true, but wth the safety of failing after some large amount of iterations.
@@ -491,5 +671,7 @@ func untilTrue(fn func() (bool, error)) error {
 			return nil
 		}
 	}
+	// a strategy claiming progress it isn't making, not anything wrong with
+	// the puzzle, so not one of the typed errors
 	return errors.New("infinite loooooooooooop detected. somebody screwed up. probably the same person who wrote the word loooooooooooop")
 }
@@ -1,9 +1,15 @@
 package sodacouplib
 
 import (
+	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"log"
+	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 const basicFormat = `1_3 _56 789
@@ -90,6 +96,19 @@ func TestNewSudokuSquare(t *testing.T) {
 			t.Errorf("expected error from bad input but none given")
 		}
 	})
+	t.Run("text around the square", func(t *testing.T) {
+		s, err := NewSudokuSquare("Puzzle from the paper:\n" + basicFormat + "Good luck!")
+		if err != nil {
+			t.Fatal("got unexpected error from labelled input:", err)
+		}
+		assert.Equal(t, 9, s.size())
+		result, _ := FormatSudoku(s.String())
+		assert.Equal(t, basicFormat, result)
+
+		formatted, err := FormatSudoku("Puzzle from the paper:\n" + basicFormat)
+		assert.NoError(t, err)
+		assert.Equal(t, basicFormat, formatted)
+	})
 	t.Run("valid string", func(t *testing.T) {
 		if s, err := NewSudokuSquare(basicFormat); err != nil {
 			t.Errorf("got unexpected error from creating table %s", err)
@@ -148,3 +167,167 @@ func TestLoadingInitialProblem(t *testing.T) {
 	assert.Equal(t, true, c2.hasCandidate(2))
 	assert.Equal(t, false, c2.hasCandidate(3))
 }
+
+func TestNextStep(t *testing.T) {
+	problem := `
+		__8 7_4 ___
+		45_ 82_ _36
+		2_3 6__ 9__
+
+		_12 _87 ___
+		_9_ 2_3 _5_
+		___ 14_ 89_
+
+		__7 __6 3_4
+		64_ _78 _21
+		___ 4_2 6__
+	`
+	t.Run("peeking should not change the square", func(t *testing.T) {
+		s, err := NewSudokuSquare(problem)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+		before := s.asTableStringWithCandidates()
+
+		st, err := s.PeekStep(SolveOptions{})
+		if err != nil {
+			t.Fatal("got unexpected error:", err)
+		}
+
+		assert.Equal(t, "Naked Single", st.Technique)
+		assert.Equal(t, 1, len(st.Placements))
+		assert.Equal(t, before, s.asTableStringWithCandidates())
+	})
+	t.Run("each step should make exactly one deduction", func(t *testing.T) {
+		s, err := NewSudokuSquare(problem)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+		for !isSolved(s) {
+			setBefore := s.SetCount()
+			st, err := s.NextStep(SolveOptions{})
+			if err != nil {
+				t.Fatal("got unexpected error:", err)
+			}
+			if st == nil {
+				t.Fatal("expected a step for an unsolved problem")
+			}
+			assert.Equal(t, setBefore+len(st.Placements), s.SetCount())
+			for _, p := range st.Placements {
+				assert.Equal(t, uint8(p.Value), s.cells[p.Row][p.Col].value)
+			}
+			for _, e := range st.Eliminations {
+				assert.Equal(t, false, s.cells[e.Row][e.Col].hasCandidate(e.Value))
+			}
+		}
+		st, err := s.NextStep(SolveOptions{})
+		assert.NoError(t, err)
+		assert.Nil(t, st)
+	})
+	t.Run("only the first deduction is applied", func(t *testing.T) {
+		s := newEmptySudoku()
+		// two hidden singles in the one row
+		for col := 0; col < 9; col++ {
+			if col != 2 {
+				s.cells[6][col].removeCandidate(4)
+			}
+			if col != 7 {
+				s.cells[6][col].removeCandidate(5)
+			}
+		}
+		set, _ := NewStrategySet("Hidden Single")
+
+		st, err := s.NextStep(SolveOptions{Strategies: set})
+		if err != nil {
+			t.Fatal("got unexpected error:", err)
+		}
+
+		assert.Equal(t, []Candidate{{6, 2, 4}}, st.Placements)
+		assert.Equal(t, true, s.cells[6][2].isSet)
+		assert.Equal(t, false, s.cells[6][7].isSet)
+	})
+}
+
+func TestSolveContext(t *testing.T) {
+	none, _ := NewStrategySet()
+	for _, backend := range []Backend{Backtracking, DancingLinks} {
+		t.Run("node limit", func(t *testing.T) {
+			// an empty square takes a guess for most cells
+			s := newEmptySudoku()
+			steps, err := s.SolveContext(context.Background(), SolveOptions{Strategies: none, Backend: backend, MaxNodes: 5})
+			assert.Equal(t, true, errors.Is(err, ErrNodeLimit))
+			assert.Equal(t, 0, len(steps))
+			assert.Equal(t, 0, s.SetCount())
+		})
+		t.Run("enough nodes", func(t *testing.T) {
+			s, _ := NewSudokuSquare(seventeenClues)
+			_, err := s.SolveContext(context.Background(), SolveOptions{Strategies: none, Backend: backend, MaxNodes: 10000})
+			assert.NoError(t, err)
+			assert.Equal(t, true, isSolved(s))
+		})
+	}
+	t.Run("strategies count as nodes", func(t *testing.T) {
+		s, _ := NewSudokuSquare(seventeenClues)
+		set, _ := NewStrategySet("Naked Single", "Hidden Single")
+		steps, err := s.SolveContext(context.Background(), SolveOptions{Strategies: set, MaxNodes: 3})
+		var spent *BudgetError
+		assert.Equal(t, true, errors.As(err, &spent))
+		assert.Equal(t, 3, spent.Nodes)
+		// what the strategies managed before then is kept
+		assert.Equal(t, 17+len(steps), s.SetCount())
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s, _ := NewSudokuSquare(seventeenClues)
+		_, err := s.SolveContext(ctx, SolveOptions{})
+		assert.Equal(t, true, errors.Is(err, context.Canceled))
+	})
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		s, _ := NewSudokuSquare(seventeenClues)
+		_, err := s.SolveContext(ctx, SolveOptions{})
+		assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+// A generated 16x16 problem, graded hard with Naked Triple the hardest
+// technique it needs.
+const sixteenProblem = `
+	_G8_ __D_ 5_B6 E4__
+	BA_9 2_8F ___1 ___6
+	3__5 G___ 2___ __7_
+	_4D_ _3_1 ____ ___2
+
+	__EF ___5 _C_7 _1_4
+	_9__ B_32 _FE_ ___8
+	6__3 ___7 B95_ F__G
+	__G2 ___6 1___ C_9_
+
+	___E _F9_ C7_G ___3
+	7___ _8__ F4__ ____
+	__4_ ____ ___9 ____
+	___B 3_5_ __6A 7___
+
+	__F_ 1_4_ __8C 3__B
+	____ _D__ ____ 16__
+	_3__ 9___ 71__ 5__F
+	_C_1 ____ _2GB 9__E
+`
+
+func BenchmarkSolve16(b *testing.B) {
+	s, err := NewSudokuSquare(sixteenProblem)
+	if err != nil {
+		b.Fatal("got unexpected error from valid input:", err)
+	}
+	// the board logged after every deduction would swamp the solving
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	for i := 0; i < b.N; i++ {
+		if _, err := s.clone().Solve(SolveOptions{}); err != nil {
+			b.Fatal("got unexpected error from solving:", err)
+		}
+	}
+}
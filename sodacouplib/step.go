@@ -0,0 +1,107 @@
+package sodacouplib
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Step is one deduction made while solving, in enough detail to explain it
+// to a person: which technique found it, where, and what it changed.
+type Step struct {
+	Technique string
+	// House is the name of the row, column or block the deduction was made
+	// in. Patterns spanning several houses name them all.
+	House string
+	// Cells are the cells that make up the pattern the technique spotted.
+	Cells        []CellRef
+	Placements   []Candidate
+	Eliminations []Candidate
+	// Proof spells out the chains behind chain techniques, one per line, as
+	// runs of candidates each implied by the one before, e.g.
+	// "0,0 != 1 -> 0,4 = 1 -> 4,4 != 1".
+	Proof []string
+}
+
+// CellRef is the position of a cell, zero based.
+type CellRef struct {
+	Row, Col int
+}
+
+// Candidate is a value in a cell, either being placed or being ruled out.
+type Candidate struct {
+	Row, Col, Value int
+}
+
+// StepFunc is handed each Step as a strategy makes it. Returning false asks
+// the strategy to stop there and return without looking for more.
+type StepFunc func(Step) bool
+
+func ignoreSteps(Step) bool {
+	return true
+}
+
+// errStopped unwinds an algorithm (and the applyTo... helpers it runs in)
+// once its StepFunc has asked it to stop. Never escapes a Strategy.
+var errStopped = errors.New("stopped by StepFunc")
+
+// report hands the step on, turning a request to stop into errStopped.
+func report(step StepFunc, st Step) error {
+	if !step(st) {
+		return errStopped
+	}
+	return nil
+}
+
+func (st Step) String() string {
+	var sb strings.Builder
+	sb.WriteString(st.Technique)
+	if st.House != "" {
+		fmt.Fprintf(&sb, " in %s", st.House)
+	}
+	sep := ": "
+	for _, p := range st.Placements {
+		fmt.Fprintf(&sb, "%s%d,%d => %d", sep, p.Row, p.Col, p.Value)
+		sep = ", "
+	}
+	for _, e := range st.Eliminations {
+		fmt.Fprintf(&sb, "%s%d,%d != %d", sep, e.Row, e.Col, e.Value)
+		sep = ", "
+	}
+	return sb.String()
+}
+
+func (st *Step) addCell(cell *SudokuCell) {
+	st.Cells = append(st.Cells, CellRef{cell.row, cell.col})
+}
+
+// place sets the cell through setCell and notes it down as a placement.
+func (st *Step) place(sud *SudokuSquare, cell *SudokuCell, val int) error {
+	if e := sud.setCell(cell.row, cell.col, val); e != nil {
+		return e
+	}
+	st.Placements = append(st.Placements, Candidate{cell.row, cell.col, val})
+	return nil
+}
+
+// eliminate removes a candidate from a cell and notes it down, if the cell
+// still had it. Reports whether anything was removed.
+func (st *Step) eliminate(cell *SudokuCell, val int) bool {
+	if !cell.hasCandidate(val) {
+		return false
+	}
+	cell.removeCandidate(val)
+	st.Eliminations = append(st.Eliminations, Candidate{cell.row, cell.col, val})
+	return true
+}
+
+// eliminateMask is eliminate for every value in the candidate mask.
+func (st *Step) eliminateMask(cell *SudokuCell, mask uint32) bool {
+	impacting := false
+	for val := 1; val <= maxSize; val++ {
+		if (1<<val)&mask > 0 {
+			impacting = st.eliminate(cell, val) || impacting
+		}
+	}
+	return impacting
+}
@@ -0,0 +1,135 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSolveSteps(t *testing.T) {
+	t.Run("naked single", func(t *testing.T) {
+		s, err := NewSudokuSquare(`
+			1_3 456 789
+			456 789 123
+			789 123 456
+
+			234 567 891
+			567 891 234
+			891 234 567
+
+			345 678 912
+			678 912 345
+			912 345 678
+		`)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+
+		steps, err := s.Solve(SolveOptions{})
+		if err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+
+		assert.Equal(t, []Step{{
+			Technique:  "Naked Single",
+			Cells:      []CellRef{{0, 1}},
+			Placements: []Candidate{{0, 1, 2}},
+		}}, steps)
+	})
+	t.Run("hidden single", func(t *testing.T) {
+		s := newEmptySudoku()
+		for col := 1; col < 9; col++ {
+			s.cells[4][col].removeCandidate(7)
+		}
+
+		var steps []Step
+		_, err := hiddenSingle(s, func(st Step) bool {
+			steps = append(steps, st)
+			return true
+		})
+		if err != nil {
+			t.Fatal("got unexpected error:", err)
+		}
+
+		assert.Equal(t, []Step{{
+			Technique:  "Hidden Single",
+			House:      "row 4",
+			Cells:      []CellRef{{4, 0}},
+			Placements: []Candidate{{4, 0, 7}},
+		}}, steps)
+	})
+	t.Run("pointing pair", func(t *testing.T) {
+		s := newEmptySudoku()
+		// only 3,4 and 3,5 can hold a 2 inside the centre block
+		for row := 3; row < 6; row++ {
+			for col := 3; col < 6; col++ {
+				if row != 3 || col == 3 {
+					s.cells[row][col].removeCandidate(2)
+				}
+			}
+		}
+
+		var steps []Step
+		_, err := pointingPair(s, func(st Step) bool {
+			steps = append(steps, st)
+			return true
+		})
+		if err != nil {
+			t.Fatal("got unexpected error:", err)
+		}
+
+		assert.Equal(t, 1, len(steps))
+		assert.Equal(t, "Pointing Pair", steps[0].Technique)
+		assert.Equal(t, "block 1 1", steps[0].House)
+		assert.Equal(t, []CellRef{{3, 4}, {3, 5}}, steps[0].Cells)
+		assert.Equal(t, []Candidate{
+			{3, 0, 2}, {3, 1, 2}, {3, 2, 2}, {3, 6, 2}, {3, 7, 2}, {3, 8, 2},
+		}, steps[0].Eliminations)
+		assert.Equal(t, 0, len(steps[0].Placements))
+	})
+	t.Run("backtracking is recorded as one step", func(t *testing.T) {
+		s, err := NewSudokuSquare(`
+			8__ ___ ___
+			__3 6__ ___
+			_7_ _9_ 2__
+
+			_5_ __7 ___
+			___ _45 7__
+			___ 1__ _3_
+
+			__1 ___ _68
+			__8 5__ _1_
+			_9_ ___ 4__
+		`)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+
+		clues := s.SetCount()
+		steps, err := s.Solve(SolveOptions{})
+		if err != nil {
+			t.Fatal("got unexpected error from solving:", err)
+		}
+
+		last := steps[len(steps)-1]
+		assert.Equal(t, "Backtracking", last.Technique)
+		placed := 0
+		for _, st := range steps {
+			placed += len(st.Placements)
+		}
+		// every empty cell is accounted for exactly once
+		assert.Equal(t, 81-clues, placed)
+	})
+}
+
+func TestStepString(t *testing.T) {
+	st := Step{
+		Technique:    "Naked Pair",
+		House:        "column 2",
+		Cells:        []CellRef{{1, 2}, {5, 2}},
+		Eliminations: []Candidate{{0, 2, 3}, {0, 2, 8}},
+	}
+	assert.Equal(t, "Naked Pair in column 2: 0,2 != 3, 0,2 != 8", st.String())
+
+	st = Step{Technique: "Naked Single", Placements: []Candidate{{4, 4, 9}}}
+	assert.Equal(t, "Naked Single: 4,4 => 9", st.String())
+}
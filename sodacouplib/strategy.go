@@ -0,0 +1,265 @@
+package sodacouplib
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Strategy is a single solving technique the heuristic solver can use.
+// Apply makes whatever progress the technique allows on the square, hands
+// each deduction to `step`, and reports whether it changed anything. If
+// `step` returns false Apply should return straight away, having changed
+// nothing beyond the steps already handed over.
+type Strategy interface {
+	Name() string
+	Apply(sud *SudokuSquare, step StepFunc) (bool, error)
+}
+
+// NewStrategy wraps a plain function as a Strategy so it can be registered.
+func NewStrategy(name string, fn func(*SudokuSquare, StepFunc) (bool, error)) Strategy {
+	return algoStrategy{name, fn}
+}
+
+type algoStrategy struct {
+	name string
+	fn   sudokuAlgo
+}
+
+func (s algoStrategy) Name() string {
+	return s.name
+}
+
+func (s algoStrategy) Apply(sud *SudokuSquare, step StepFunc) (bool, error) {
+	changes, err := s.fn(sud, step)
+	if err == errStopped {
+		return true, nil
+	}
+	return changes, err
+}
+
+// The techniques that come with the package, roughly ordered from the
+// cheapest/simplest to the most involved.
+func builtinStrategies() []Strategy {
+	return []Strategy{
+		NewStrategy("Naked Single", nakedSingle),
+		NewStrategy("Hidden Single", hiddenSingle),
+		NewStrategy("Cage Sum", cageSum),
+		NewStrategy("Pointing Pair", pointingPair),
+		NewStrategy("Claiming Pair", claimingPair),
+		NewStrategy("Naked Pair", nakedPair),
+		NewStrategy("Hidden Pair", hiddenPair),
+		NewStrategy("Naked Triple", nakedTriple),
+		NewStrategy("Hidden Triple", hiddenTriple),
+		NewStrategy("Naked Quad", nakedQuad),
+		NewStrategy("Hidden Quad", hiddenQuad),
+		NewStrategy("Skyscraper", skyscraper),
+		NewStrategy("2-String Kite", twoStringKite),
+		NewStrategy("Turbot Fish", turbot),
+		NewStrategy("Empty Rectangle", emptyRectangle),
+		NewStrategy("X-Wing", xWing),
+		NewStrategy("Swordfish", swordfish),
+		NewStrategy("Jellyfish", jellyfish),
+		NewStrategy("XY-Wing", xyWing),
+		NewStrategy("XYZ-Wing", xyzWing),
+		NewStrategy("Finned X-Wing", finnedXWing),
+		NewStrategy("Finned Swordfish", finnedSwordfish),
+		NewStrategy("Finned Jellyfish", finnedJellyfish),
+		NewStrategy("Sue de Coq", sueDeCoq),
+		NewStrategy("Simple Coloring", simpleColoring),
+		NewStrategy("X-Cycle", xCycle),
+		NewStrategy("3D Medusa", medusa),
+		NewStrategy("Unique Rectangle", uniqueRectangles),
+		NewStrategy("Hidden Unique Rectangle", hiddenUniqueRectangle),
+		NewStrategy("BUG+1", bugPlusOne),
+		NewStrategy("ALS-XZ", alsXZ),
+		NewStrategy("ALS-XY-Wing", alsXYWing),
+		NewStrategy("Death Blossom", deathBlossom),
+		NewStrategy("AIC", aic),
+		NewStrategy("Cell Forcing Chain", cellForcingChain),
+		NewStrategy("House Forcing Chain", houseForcingChain),
+	}
+}
+
+// The registry is every strategy known by name. Built-ins are registered up
+// front, callers can add their own with RegisterStrategy.
+var registry = struct {
+	sync.Mutex
+	strategies []Strategy
+}{strategies: builtinStrategies()}
+
+// RegisterStrategy makes a strategy available by name to NewStrategySet and
+// appends it to the end of DefaultStrategies, so Solve, NextStep and
+// PeekStep use it when no StrategySet is given. Grade uses it too, tried
+// among the built-ins as if it were a technique of weight 10. Generation
+// doesn't: it sticks to a fixed set of basic techniques unless it's given a
+// StrategySet (see GenerateProblemContext).
+func RegisterStrategy(s Strategy) error {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, r := range registry.strategies {
+		if r.Name() == s.Name() {
+			return fmt.Errorf("strategy %q already registered", s.Name())
+		}
+	}
+	registry.strategies = append(registry.strategies, s)
+	return nil
+}
+
+// LookupStrategy finds a registered strategy by name.
+func LookupStrategy(name string) (Strategy, bool) {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, s := range registry.strategies {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// RegisteredStrategies lists the names of every registered strategy in
+// registration order.
+func RegisteredStrategies() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	names := make([]string, len(registry.strategies))
+	for i, s := range registry.strategies {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// StrategySet is an ordered list of strategies, each of which can be
+// switched on or off, that the heuristic solver works through.
+type StrategySet struct {
+	entries []strategyEntry
+}
+
+type strategyEntry struct {
+	strategy Strategy
+	enabled  bool
+}
+
+// DefaultStrategies is every registered strategy in registration order,
+// all enabled except those that assume the puzzle has only one solution
+// (see SolveOptions.AssumeUnique).
+func DefaultStrategies() *StrategySet {
+	set, err := NewStrategySet(RegisteredStrategies()...)
+	if err != nil {
+		panic(err) // names just came from the registry
+	}
+	for i, e := range set.entries {
+		set.entries[i].enabled = !uniquenessStrategies[e.strategy.Name()]
+	}
+	return set
+}
+
+// NewStrategySet builds a set from registered strategy names in the order given.
+func NewStrategySet(names ...string) (*StrategySet, error) {
+	var set StrategySet
+	for _, name := range names {
+		s, ok := LookupStrategy(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown strategy %q", name)
+		}
+		if set.find(name) >= 0 {
+			return nil, fmt.Errorf("strategy %q listed twice", name)
+		}
+		set.entries = append(set.entries, strategyEntry{s, true})
+	}
+	return &set, nil
+}
+
+// Names lists all strategies in the set in order, enabled or not.
+func (set *StrategySet) Names() []string {
+	names := make([]string, len(set.entries))
+	for i, e := range set.entries {
+		names[i] = e.strategy.Name()
+	}
+	return names
+}
+
+// Enabled returns the strategies the solver will use, in order.
+func (set *StrategySet) Enabled() []Strategy {
+	var strategies []Strategy
+	for _, e := range set.entries {
+		if e.enabled {
+			strategies = append(strategies, e.strategy)
+		}
+	}
+	return strategies
+}
+
+// IsEnabled reports whether the named strategy is in the set and switched on.
+func (set *StrategySet) IsEnabled(name string) bool {
+	i := set.find(name)
+	return i >= 0 && set.entries[i].enabled
+}
+
+// Enable switches a strategy on, adding it to the end of the set if it is
+// registered but not in the set yet.
+func (set *StrategySet) Enable(name string) error {
+	if i := set.find(name); i >= 0 {
+		set.entries[i].enabled = true
+		return nil
+	}
+	s, ok := LookupStrategy(name)
+	if !ok {
+		return fmt.Errorf("unknown strategy %q", name)
+	}
+	set.entries = append(set.entries, strategyEntry{s, true})
+	return nil
+}
+
+// Disable switches a strategy off while keeping its place in the order.
+func (set *StrategySet) Disable(name string) error {
+	i := set.find(name)
+	if i < 0 {
+		return fmt.Errorf("strategy %q not in set", name)
+	}
+	set.entries[i].enabled = false
+	return nil
+}
+
+// Reorder moves the named strategies to the front of the set in the order
+// given. Strategies not named keep their relative order after them.
+func (set *StrategySet) Reorder(names ...string) error {
+	front := make([]strategyEntry, 0, len(set.entries))
+	for _, name := range names {
+		i := set.find(name)
+		if i < 0 {
+			return fmt.Errorf("strategy %q not in set", name)
+		}
+		for _, e := range front {
+			if e.strategy.Name() == name {
+				return fmt.Errorf("strategy %q listed twice", name)
+			}
+		}
+		front = append(front, set.entries[i])
+	}
+	for _, e := range set.entries {
+		named := false
+		for _, name := range names {
+			named = named || e.strategy.Name() == name
+		}
+		if !named {
+			front = append(front, e)
+		}
+	}
+	set.entries = front
+	return nil
+}
+
+// Copy of the set that can be changed without changing the original.
+func (set *StrategySet) clone() *StrategySet {
+	return &StrategySet{append([]strategyEntry(nil), set.entries...)}
+}
+
+func (set *StrategySet) find(name string) int {
+	for i, e := range set.entries {
+		if e.strategy.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
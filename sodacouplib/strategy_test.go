@@ -0,0 +1,150 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDefaultStrategies(t *testing.T) {
+	set := DefaultStrategies()
+	names := set.Names()
+
+	// every built-in technique should be in there
+	for _, name := range []string{
+		"Naked Single", "Hidden Single", "Cage Sum", "Pointing Pair", "Claiming Pair",
+		"Naked Pair", "Hidden Pair", "Naked Triple", "Hidden Triple", "Naked Quad", "Hidden Quad", "Skyscraper", "2-String Kite", "Turbot Fish",
+		"Empty Rectangle", "X-Wing", "Swordfish", "Jellyfish",
+		"XY-Wing", "XYZ-Wing", "Finned X-Wing", "Finned Swordfish", "Finned Jellyfish", "Sue de Coq",
+		"Simple Coloring", "X-Cycle", "3D Medusa", "ALS-XZ", "ALS-XY-Wing", "Death Blossom", "AIC", "Cell Forcing Chain", "House Forcing Chain",
+	} {
+		assert.Contains(t, names, name)
+		assert.Equal(t, true, set.IsEnabled(name))
+	}
+	// those assuming a unique solution are there but switched off
+	for _, name := range []string{"Unique Rectangle", "Hidden Unique Rectangle", "BUG+1"} {
+		assert.Contains(t, names, name)
+		assert.Equal(t, false, set.IsEnabled(name))
+	}
+	assert.Equal(t, len(names)-3, len(set.Enabled()))
+}
+
+func TestStrategySet(t *testing.T) {
+	t.Run("unknown names should give error", func(t *testing.T) {
+		if _, err := NewStrategySet("Naked Single", "Guesswork"); err == nil {
+			t.Error("expected error from unknown strategy but none given")
+		}
+		if _, err := NewStrategySet("Naked Single", "Naked Single"); err == nil {
+			t.Error("expected error from duplicate strategy but none given")
+		}
+	})
+	t.Run("disable and enable", func(t *testing.T) {
+		set, err := NewStrategySet("Naked Single", "Hidden Single", "X-Wing")
+		if err != nil {
+			t.Fatal("got unexpected error:", err)
+		}
+
+		assert.NoError(t, set.Disable("Hidden Single"))
+		assert.Equal(t, false, set.IsEnabled("Hidden Single"))
+		assert.Equal(t, []string{"Naked Single", "Hidden Single", "X-Wing"}, set.Names())
+		assert.Equal(t, 2, len(set.Enabled()))
+
+		assert.NoError(t, set.Enable("Hidden Single"))
+		assert.NoError(t, set.Enable("Naked Pair"))
+		assert.Equal(t, []string{"Naked Single", "Hidden Single", "X-Wing", "Naked Pair"}, set.Names())
+		assert.Equal(t, 4, len(set.Enabled()))
+
+		assert.Error(t, set.Disable("Hidden Pair"))
+		assert.Error(t, set.Enable("Guesswork"))
+	})
+	t.Run("reorder", func(t *testing.T) {
+		set, err := NewStrategySet("Naked Single", "Hidden Single", "Naked Pair", "X-Wing")
+		if err != nil {
+			t.Fatal("got unexpected error:", err)
+		}
+
+		assert.NoError(t, set.Reorder("X-Wing", "Hidden Single"))
+		assert.Equal(t, []string{"X-Wing", "Hidden Single", "Naked Single", "Naked Pair"}, set.Names())
+
+		assert.Error(t, set.Reorder("Hidden Pair"))
+		assert.Error(t, set.Reorder("X-Wing", "X-Wing"))
+	})
+}
+
+// Puts the registry back the way it was once the test is done, as it's
+// shared by the whole test binary.
+func keepRegistry(t *testing.T) {
+	registry.Lock()
+	saved := registry.strategies
+	registry.Unlock()
+	t.Cleanup(func() {
+		registry.Lock()
+		registry.strategies = saved
+		registry.Unlock()
+	})
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	called := 0
+	custom := NewStrategy("Test Counter", func(*SudokuSquare, StepFunc) (bool, error) {
+		called++
+		return false, nil
+	})
+	keepRegistry(t)
+
+	if err := RegisterStrategy(custom); err != nil {
+		t.Fatal("got unexpected error registering:", err)
+	}
+	assert.Error(t, RegisterStrategy(custom))
+	assert.Contains(t, RegisteredStrategies(), "Test Counter")
+
+	set, err := NewStrategySet("Test Counter")
+	if err != nil {
+		t.Fatal("got unexpected error:", err)
+	}
+	s, err := NewSudokuSquare(basicFormat)
+	if err != nil {
+		t.Fatal("got unexpected error from valid input:", err)
+	}
+	if _, err = s.Solve(SolveOptions{Strategies: set}); err != nil {
+		t.Fatal("got unexpected error from solving:", err)
+	}
+	assert.Equal(t, 1, called)
+	assert.Equal(t, true, isSolved(s))
+}
+
+func TestSolveWithStrategies(t *testing.T) {
+	problem := `
+		___ __5 __9
+		_8_ 4__ ___
+		___ _7_ __4
+
+		_32 748 9__
+		__6 539 _42
+		___ 216 37_
+
+		__4 _57 293
+		__5 _21 4_7
+		2__ __4 ___
+	`
+	t.Run("hidden singles alone get stuck", func(t *testing.T) {
+		s, err := NewSudokuSquare(problem)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+		set, _ := NewStrategySet("Hidden Single")
+		_, err = trySolveWithHeuristics(s, set, ignoreSteps, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, false, s.cells[0][4].isSet)
+	})
+	t.Run("adding x-wing makes progress", func(t *testing.T) {
+		s, err := NewSudokuSquare(problem)
+		if err != nil {
+			t.Fatal("got unexpected error from valid input:", err)
+		}
+		set, _ := NewStrategySet("X-Wing", "Hidden Single")
+		_, err = trySolveWithHeuristics(s, set, ignoreSteps, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, true, s.cells[0][4].isSet)
+		assert.Equal(t, uint8(8), s.cells[0][4].value)
+	})
+}
@@ -0,0 +1,139 @@
+package sodacouplib
+
+import (
+	"math/bits"
+)
+
+// Locked subsets are named after their kind and how many cells they take.
+var subsetNames = map[int]string{
+	2: "Pair",
+	3: "Triple",
+	4: "Quad",
+}
+
+// if two values are unique to two cells already then they cannot go in elsewhere
+func nakedPair(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return lockedSubset(sud, step, true, 2)
+}
+
+// if two values can only go into the same two cells then
+// remove other values from those two cells
+func hiddenPair(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return lockedSubset(sud, step, false, 2)
+}
+
+// nakedPair with three values between three cells.
+func nakedTriple(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return lockedSubset(sud, step, true, 3)
+}
+
+// hiddenPair with three values that only go in three cells.
+func hiddenTriple(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return lockedSubset(sud, step, false, 3)
+}
+
+// nakedPair with four values between four cells.
+func nakedQuad(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return lockedSubset(sud, step, true, 4)
+}
+
+// hiddenPair with four values that only go in four cells.
+func hiddenQuad(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return lockedSubset(sud, step, false, 4)
+}
+
+// A locked subset is `size` cells of a house and `size` values that have to
+// go in them. Naked, the cells have nothing but those values between them,
+// so the values can be removed from the rest of the house. Hidden, the
+// values have nowhere else in the house to go, so anything else can be
+// removed from the cells. The step's technique says which it found, as
+// "Naked Triple" or "Hidden Quad" say.
+func lockedSubset(sud *SudokuSquare, step StepFunc, naked bool, size int) (bool, error) {
+	technique := "Hidden " + subsetNames[size]
+	find := hiddenSubsets
+	if naked {
+		technique = "Naked " + subsetNames[size]
+		find = nakedSubsets
+	}
+	return applyToNonagons(sud, func(nona nonagon) (bool, error) {
+		changes := false
+		var err error
+		find(sud, nona, size, func(cells, values uint32) {
+			if err != nil {
+				return
+			}
+			st := Step{Technique: technique, House: nona.name}
+			impacting := false
+			for i, cell := range nona.cells {
+				if cells&(1<<uint(i)) != 0 {
+					st.addCell(cell)
+					if !naked {
+						impacting = st.eliminateMask(cell, cell.candidates&^values) || impacting
+					}
+				} else if naked && !cell.isSet {
+					impacting = st.eliminateMask(cell, values) || impacting
+				}
+			}
+			if impacting {
+				changes = true
+				err = report(step, st)
+			}
+		})
+		return changes, err
+	})
+}
+
+// Calls fn with each naked subset of the house, its cells as a mask of
+// their places in the house and its values as a candidate mask.
+func nakedSubsets(sud *SudokuSquare, nona nonagon, size int, fn func(cells, values uint32)) {
+	var fits uint32 // the cells with few enough candidates to be in one
+	for i, cell := range nona.cells {
+		if !cell.isSet && bits.OnesCount32(cell.candidates) >= 2 && bits.OnesCount32(cell.candidates) <= size {
+			fits |= 1 << uint(i)
+		}
+	}
+	eachSubset(fits, size, func(cells uint32) {
+		var values uint32
+		for i, cell := range nona.cells {
+			if cells&(1<<uint(i)) != 0 {
+				if cell.isSet {
+					return // set by an earlier step
+				}
+				values |= cell.candidates
+			}
+		}
+		if bits.OnesCount32(values) == size {
+			fn(cells, values)
+		}
+	})
+}
+
+// Calls fn with each hidden subset of the house, as nakedSubsets.
+func hiddenSubsets(sud *SudokuSquare, nona nonagon, size int, fn func(cells, values uint32)) {
+	places := func(val int) uint32 {
+		var places uint32
+		for i, cell := range nona.cells {
+			if cell.hasCandidate(val) {
+				places |= 1 << uint(i)
+			}
+		}
+		return places
+	}
+	var fits uint32 // the values with few enough places to be in one
+	for val := 1; val <= sud.size(); val++ {
+		if count := bits.OnesCount32(places(val)); count >= 2 && count <= size {
+			fits |= 1 << uint(val)
+		}
+	}
+	eachSubset(fits, size, func(values uint32) {
+		var cells uint32
+		for val := 1; val <= sud.size(); val++ {
+			if values&(1<<uint(val)) != 0 {
+				cells |= places(val)
+			}
+		}
+		if bits.OnesCount32(cells) == size {
+			fn(cells, values)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNakedQuad(t *testing.T) {
+	s := newEmptySudoku()
+	s.cells[0][0].candidates = candidatesOf(1, 2)
+	s.cells[0][3].candidates = candidatesOf(2, 3)
+	s.cells[0][5].candidates = candidatesOf(3, 4)
+	s.cells[0][8].candidates = candidatesOf(1, 4)
+	noOpCheck(t, nakedTriple, s)
+
+	steps := collectSteps(t, nakedQuad, s)
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, "Naked Quad", steps[0].Technique)
+	assert.Equal(t, "row 0", steps[0].House)
+	assert.Equal(t, []CellRef{{0, 0}, {0, 3}, {0, 5}, {0, 8}}, steps[0].Cells)
+	assert.Equal(t, 5*4, len(steps[0].Eliminations))
+	for _, col := range []int{1, 2, 4, 6, 7} {
+		assert.Equal(t, candidatesOf(5, 6, 7, 8, 9), s.cells[0][col].candidates)
+	}
+	noOpCheck(t, nakedQuad, s)
+}
+
+func TestHiddenTriple(t *testing.T) {
+	s := newEmptySudoku()
+	// 1, 2 and 3 only go in columns 0, 4 and 8 of row 0
+	for _, val := range []int{1, 2, 3} {
+		keepOnly(s, val, rowRefs(0), CellRef{0, 0}, CellRef{0, 4}, CellRef{0, 8})
+	}
+	s.cells[0][4].removeCandidate(3)
+	noOpCheck(t, hiddenPair, s)
+
+	assert.Equal(t, []Step{{
+		Technique: "Hidden Triple",
+		House:     "row 0",
+		Cells:     []CellRef{{0, 0}, {0, 4}, {0, 8}},
+		Eliminations: []Candidate{
+			{0, 0, 4}, {0, 0, 5}, {0, 0, 6}, {0, 0, 7}, {0, 0, 8}, {0, 0, 9},
+			{0, 4, 4}, {0, 4, 5}, {0, 4, 6}, {0, 4, 7}, {0, 4, 8}, {0, 4, 9},
+			{0, 8, 4}, {0, 8, 5}, {0, 8, 6}, {0, 8, 7}, {0, 8, 8}, {0, 8, 9},
+		},
+	}}, collectSteps(t, hiddenTriple, s))
+	assert.Equal(t, candidatesOf(1, 2), s.cells[0][4].candidates)
+	noOpCheck(t, hiddenTriple, s)
+}
+
+func TestHiddenQuad(t *testing.T) {
+	s := newEmptySudoku()
+	for _, val := range []int{1, 2, 3, 4} {
+		keepOnly(s, val, colRefs(2), CellRef{1, 2}, CellRef{3, 2}, CellRef{5, 2}, CellRef{7, 2})
+	}
+	noOpCheck(t, hiddenTriple, s)
+
+	steps := collectSteps(t, hiddenQuad, s)
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, "Hidden Quad", steps[0].Technique)
+	assert.Equal(t, "column 2", steps[0].House)
+	for _, row := range []int{1, 3, 5, 7} {
+		assert.Equal(t, candidatesOf(1, 2, 3, 4), s.cells[row][2].candidates)
+	}
+	noOpCheck(t, hiddenQuad, s)
+}
+
+func TestLockedSubsetSizes(t *testing.T) {
+	s := newEmptySudoku()
+	s.cells[0][0].candidates = candidatesOf(1, 2)
+	s.cells[0][1].candidates = candidatesOf(1, 2)
+
+	// a pair isn't a triple or a quad
+	noOpCheck(t, nakedTriple, s)
+	noOpCheck(t, nakedQuad, s)
+	steps := collectSteps(t, nakedPair, s)
+	// one step for each of row 0 and block 0 0
+	assert.Equal(t, 2, len(steps))
+	assert.Equal(t, "Naked Pair", steps[0].Technique)
+}
@@ -0,0 +1,169 @@
+package sodacouplib
+
+import (
+	"math/bits"
+)
+
+// The most cells looked for on each side of a Sue de Coq.
+const maxSueDeCoqSide = 3
+
+// Sue de Coq: two or three cells where a block and a line cross, holding
+// between them at least two values more than there are cells. Take some
+// cells from the rest of the block, and some from the rest of the line,
+// with nothing but those values in them and no value in both lots. If there
+// are as many cells altogether as values, each value is in exactly one of
+// them (the block's cells can't share a value with the line's, and neither
+// can share one with the crossing). So the block's values stay in the
+// block, the line's in the line and the rest in the crossing: the rest of
+// the block can lose all the values but the line's, and the rest of the
+// line all but the block's.
+func sueDeCoq(sud *SudokuSquare, step StepFunc) (bool, error) {
+	n := sud.size()
+	geo := sud.geo
+	changes := false
+	for _, b := range geo.blocks {
+		block := geo.houses[b]
+		rows, cols := linesThrough(block, n)
+		lines := append([]int(nil), rows...) // rows are the first houses
+		for _, col := range cols {
+			lines = append(lines, n+col) // and columns the next
+		}
+		for _, l := range lines {
+			impacting, err := sueDeCoqAt(sud, step, block, geo.houses[l])
+			changes = changes || impacting
+			if err != nil {
+				return changes, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+func sueDeCoqAt(sud *SudokuSquare, step StepFunc, block, line house) (bool, error) {
+	var crossing, blockRest, lineRest []int
+	for _, idx := range block.cells {
+		if sud.cellAt(idx).isSet {
+			continue
+		}
+		if containsInt(line.cells, idx) {
+			crossing = append(crossing, idx)
+		} else {
+			blockRest = append(blockRest, idx)
+		}
+	}
+	for _, idx := range line.cells {
+		if !sud.cellAt(idx).isSet && !containsInt(block.cells, idx) {
+			lineRest = append(lineRest, idx)
+		}
+	}
+	changes := false
+	for size := 2; size <= len(crossing) && size <= 3; size++ {
+		var err error
+		eachSubset(1<<uint(len(crossing))-1, size, func(pick uint32) {
+			if err != nil {
+				return
+			}
+			cells := pickCells(crossing, pick)
+			var values uint32
+			for _, idx := range cells {
+				values |= sud.cellAt(idx).candidates
+			}
+			extra := bits.OnesCount32(values) - size // cells needed outside
+			if extra < 2 {
+				return
+			}
+			st := sueDeCoqStep(sud, block, line, cells, values, extra, blockRest, lineRest)
+			if st != nil {
+				changes = true
+				err = report(step, *st)
+			}
+		})
+		if err != nil {
+			return true, err
+		}
+	}
+	return changes, nil
+}
+
+// The cells of list at the places set in pick.
+func pickCells(list []int, pick uint32) []int {
+	var cells []int
+	for i, idx := range list {
+		if pick&(1<<uint(i)) != 0 {
+			cells = append(cells, idx)
+		}
+	}
+	return cells
+}
+
+// Looks for `extra` cells from the rest of the block and line that make a
+// Sue de Coq with the crossing cells, and makes the step if there are any
+// eliminations.
+func sueDeCoqStep(sud *SudokuSquare, block, line house, crossing []int, values uint32, extra int, blockRest, lineRest []int) *Step {
+	// the cells with nothing but the crossing's values
+	fits := func(rest []int) []int {
+		var fits []int
+		for _, idx := range rest {
+			cell := sud.cellAt(idx)
+			if !cell.isSet && bits.OnesCount32(cell.candidates) >= 2 && cell.candidates&^values == 0 {
+				fits = append(fits, idx)
+			}
+		}
+		return fits
+	}
+	inBlock, inLine := fits(blockRest), fits(lineRest)
+	union := func(cells []int) uint32 {
+		var mask uint32
+		for _, idx := range cells {
+			mask |= sud.cellAt(idx).candidates
+		}
+		return mask
+	}
+	for kb := 1; kb < extra && kb <= maxSueDeCoqSide && kb <= len(inBlock); kb++ {
+		kl := extra - kb
+		if kl > maxSueDeCoqSide || kl > len(inLine) {
+			continue
+		}
+		var found *Step
+		eachSubset(1<<uint(len(inBlock))-1, kb, func(pickB uint32) {
+			if found != nil {
+				return
+			}
+			blockCells := pickCells(inBlock, pickB)
+			blockValues := union(blockCells)
+			eachSubset(1<<uint(len(inLine))-1, kl, func(pickL uint32) {
+				if found != nil {
+					return
+				}
+				lineCells := pickCells(inLine, pickL)
+				lineValues := union(lineCells)
+				if blockValues&lineValues != 0 {
+					return
+				}
+				st := Step{Technique: "Sue de Coq", House: block.name + " & " + line.name}
+				pattern := append(append(append([]int(nil), crossing...), blockCells...), lineCells...)
+				for _, idx := range pattern {
+					st.addCell(sud.cellAt(idx))
+				}
+				// the crossing's other cells are in both, so lose everything
+				for _, idx := range block.cells {
+					if !containsInt(pattern, idx) {
+						st.eliminateMask(sud.cellAt(idx), values&^lineValues)
+					}
+				}
+				for _, idx := range line.cells {
+					if !containsInt(pattern, idx) {
+						st.eliminateMask(sud.cellAt(idx), values&^blockValues)
+					}
+				}
+				if len(st.Eliminations) > 0 {
+					found = &st
+				}
+			})
+		})
+		if found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSueDeCoq(t *testing.T) {
+	// 1 to 4 in the crossing 0,0 and 0,1, 1 and 2 in 1,0 of the block and
+	// 3 and 4 in 0,5 of the row
+	s := newEmptySudoku()
+	s.cells[0][0].candidates = candidatesOf(1, 2, 3, 4)
+	s.cells[0][1].candidates = candidatesOf(1, 2, 3)
+	s.cells[1][0].candidates = candidatesOf(1, 2)
+	s.cells[0][5].candidates = candidatesOf(3, 4)
+
+	steps := collectSteps(t, sueDeCoq, s)
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, "Sue de Coq", steps[0].Technique)
+	assert.Equal(t, "block 0 0 & row 0", steps[0].House)
+	assert.Equal(t, []CellRef{{0, 0}, {0, 1}, {1, 0}, {0, 5}}, steps[0].Cells)
+	// the block's other cells lose 1 and 2, the row's 3 and 4, 0,2 all four
+	assert.Equal(t, candidatesOf(5, 6, 7, 8, 9), s.cells[0][2].candidates)
+	assert.Equal(t, candidatesOf(3, 4, 5, 6, 7, 8, 9), s.cells[2][2].candidates)
+	assert.Equal(t, candidatesOf(1, 2, 5, 6, 7, 8, 9), s.cells[0][8].candidates)
+	assert.Equal(t, 5*2+4+5*2, len(steps[0].Eliminations))
+	noOpCheck(t, sueDeCoq, s)
+}
+
+func TestSueDeCoqNeedsDisjointValues(t *testing.T) {
+	// with 2 in the block cell and the row cell there's no telling which side
+	// has it
+	s := newEmptySudoku()
+	s.cells[0][0].candidates = candidatesOf(1, 2, 3, 4)
+	s.cells[0][1].candidates = candidatesOf(1, 2, 3)
+	s.cells[1][0].candidates = candidatesOf(1, 2)
+	s.cells[0][5].candidates = candidatesOf(2, 3, 4)
+	noOpCheck(t, sueDeCoq, s)
+}
@@ -0,0 +1,382 @@
+package sodacouplib
+
+import (
+	"math/bits"
+)
+
+// The techniques here all lean on the puzzle having just the one solution:
+// they rule out anything that would leave two values free to swap round
+// (a deadly pattern), as that would make a second solution. They're wrong
+// for puzzles with several solutions, so DefaultStrategies leaves them off
+// and SolveOptions.AssumeUnique turns them on.
+var uniquenessStrategies = map[string]bool{
+	"Unique Rectangle":        true,
+	"Hidden Unique Rectangle": true,
+	"BUG+1":                   true,
+}
+
+// Whether the only rules are that rows, columns and blocks can't repeat a
+// value. Cage sums and diagonals can tell apart the values in a deadly
+// pattern, so it isn't deadly any more.
+func (g *geometry) allowsDeadlyPatterns() bool {
+	return len(g.variant.Cages) == 0 && !g.variant.Diagonals
+}
+
+// A unique rectangle is four cells at the corners of a rectangle, spread
+// over two rows, two columns and two blocks, that could all be a or b. If
+// all four were only a or b they could be swapped round, so something else
+// has to go in at least one of them.
+type uniqueRectangle struct {
+	corners [4]int // row*size+col, clockwise from the top left
+	mask    uint32 // a and b
+}
+
+// Calls fn with every rectangle that could be a unique rectangle for each
+// pair of values, stopping at the first error.
+func forEachRectangle(sud *SudokuSquare, fn func(ur uniqueRectangle) error) error {
+	n := sud.size()
+	geo := sud.geo
+	for r1 := 0; r1 < n; r1++ {
+		for r2 := r1 + 1; r2 < n; r2++ {
+			for c1 := 0; c1 < n; c1++ {
+				for c2 := c1 + 1; c2 < n; c2++ {
+					corners := [4]int{r1*n + c1, r1*n + c2, r2*n + c2, r2*n + c1}
+					common := allCandidates(n)
+					blocks := make(map[int]bool)
+					for _, idx := range corners {
+						cell := sud.cellAt(idx)
+						if cell.isSet {
+							common = 0
+						}
+						common &= cell.candidates
+						blocks[geo.cellBlock[idx]] = true
+					}
+					if len(blocks) != 2 || bits.OnesCount32(common) < 2 {
+						continue
+					}
+					for a := 1; a <= n; a++ {
+						for b := a + 1; b <= n; b++ {
+							mask := uint32(1<<a | 1<<b)
+							if common&mask != mask {
+								continue
+							}
+							if err := fn(uniqueRectangle{corners, mask}); err != nil {
+								return err
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// The Unique Rectangle types, going by what's in the corners other than a
+// and b:
+//
+//  1. Only one corner has anything else, so that has to go in it and a and
+//     b can go from it.
+//  2. Two corners on one side have the one extra value c, so c is in one of
+//     them and can go from every cell that sees both.
+//  3. Two corners on one side have extra values which, taken as one cell,
+//     make a naked subset with other cells in a house they share, so those
+//     values can go from the rest of the house.
+//  4. Two corners on one side share a house that only has a in those
+//     corners. One of them is a, so neither can be b.
+func uniqueRectangles(sud *SudokuSquare, step StepFunc) (bool, error) {
+	if !sud.geo.allowsDeadlyPatterns() {
+		return false, nil
+	}
+	changes := false
+	err := forEachRectangle(sud, func(ur uniqueRectangle) error {
+		for _, fn := range []func(*SudokuSquare, uniqueRectangle) Step{
+			uniqueRectangle1, uniqueRectangle2, uniqueRectangle3, uniqueRectangle4,
+		} {
+			st := fn(sud, ur)
+			if len(st.Eliminations) > 0 {
+				changes = true
+				if e := report(step, st); e != nil {
+					return e
+				}
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+func (ur uniqueRectangle) step(sud *SudokuSquare, technique string) Step {
+	st := Step{Technique: technique}
+	for _, idx := range ur.corners {
+		st.addCell(sud.cellAt(idx))
+	}
+	return st
+}
+
+// The corners holding nothing but a and b.
+func (ur uniqueRectangle) floor(sud *SudokuSquare) []int {
+	var floor []int
+	for _, idx := range ur.corners {
+		if sud.cellAt(idx).candidates == ur.mask {
+			floor = append(floor, idx)
+		}
+	}
+	return floor
+}
+
+// The other two corners when two corners on one side of the rectangle hold
+// just a and b, and whether there are such.
+func (ur uniqueRectangle) roof(sud *SudokuSquare) (int, int, bool) {
+	floor := ur.floor(sud)
+	if len(floor) != 2 {
+		return 0, 0, false
+	}
+	for i, idx := range ur.corners {
+		next, opposite, prev := ur.corners[(i+1)%4], ur.corners[(i+2)%4], ur.corners[(i+3)%4]
+		if idx == floor[0] && (next == floor[1] || prev == floor[1]) {
+			if next == floor[1] {
+				return opposite, prev, true
+			}
+			return next, opposite, true
+		}
+	}
+	return 0, 0, false // the floor corners are diagonally opposite
+}
+
+func uniqueRectangle1(sud *SudokuSquare, ur uniqueRectangle) Step {
+	st := ur.step(sud, "Unique Rectangle Type 1")
+	if len(ur.floor(sud)) != 3 {
+		return st
+	}
+	for _, idx := range ur.corners {
+		if cell := sud.cellAt(idx); cell.candidates != ur.mask {
+			st.eliminateMask(cell, ur.mask)
+		}
+	}
+	return st
+}
+
+func uniqueRectangle2(sud *SudokuSquare, ur uniqueRectangle) Step {
+	st := ur.step(sud, "Unique Rectangle Type 2")
+	r1, r2, ok := ur.roof(sud)
+	if !ok {
+		return st
+	}
+	extra := sud.cellAt(r1).candidates &^ ur.mask
+	if bits.OnesCount32(extra) != 1 || sud.cellAt(r2).candidates != sud.cellAt(r1).candidates {
+		return st
+	}
+	c := bits.TrailingZeros32(extra)
+	for _, idx := range sud.geo.peers[r1] {
+		if idx != r2 && sud.geo.sees(idx, r2) {
+			st.eliminate(sud.cellAt(idx), c)
+		}
+	}
+	return st
+}
+
+func uniqueRectangle3(sud *SudokuSquare, ur uniqueRectangle) Step {
+	st := ur.step(sud, "Unique Rectangle Type 3")
+	r1, r2, ok := ur.roof(sud)
+	if !ok {
+		return st
+	}
+	extra := (sud.cellAt(r1).candidates | sud.cellAt(r2).candidates) &^ ur.mask
+	k := bits.OnesCount32(extra)
+	if k < 2 || k > 4 {
+		return st
+	}
+	for _, h := range sharedHouses(sud.geo, r1, r2) {
+		// cells of the house, other than the roof, that fit in the extras
+		var fits []int
+		for _, idx := range sud.geo.houses[h].cells {
+			cell := sud.cellAt(idx)
+			if idx != r1 && idx != r2 && !cell.isSet && cell.candidates&^extra == 0 {
+				fits = append(fits, idx)
+			}
+		}
+		// k-1 of them with the roof make a naked subset of k cells
+		var subset []int
+		eachSubset(1<<uint(len(fits))-1, k-1, func(pick uint32) {
+			if subset != nil {
+				return
+			}
+			cells := []int{r1, r2}
+			for i, idx := range fits {
+				if pick&(1<<uint(i)) != 0 {
+					cells = append(cells, idx)
+				}
+			}
+			for _, idx := range sud.geo.houses[h].cells {
+				if !containsInt(cells, idx) && sud.cellAt(idx).candidates&extra != 0 && !sud.cellAt(idx).isSet {
+					subset = cells
+					return
+				}
+			}
+		})
+		if subset == nil {
+			continue
+		}
+		st.House = sud.geo.houses[h].name
+		for _, idx := range subset[2:] {
+			st.addCell(sud.cellAt(idx))
+		}
+		for _, idx := range sud.geo.houses[h].cells {
+			if !containsInt(subset, idx) {
+				st.eliminateMask(sud.cellAt(idx), extra)
+			}
+		}
+		return st
+	}
+	return st
+}
+
+func uniqueRectangle4(sud *SudokuSquare, ur uniqueRectangle) Step {
+	st := ur.step(sud, "Unique Rectangle Type 4")
+	r1, r2, ok := ur.roof(sud)
+	if !ok {
+		return st
+	}
+	for _, h := range sharedHouses(sud.geo, r1, r2) {
+		for val := 1; val <= sud.size(); val++ {
+			if ur.mask&(1<<val) == 0 || !onlyIn(sud, h, val, r1, r2) {
+				continue
+			}
+			other := bits.TrailingZeros32(ur.mask &^ (1 << val))
+			st.eliminate(sud.cellAt(r1), other)
+			st.eliminate(sud.cellAt(r2), other)
+			if len(st.Eliminations) > 0 {
+				st.House = sud.geo.houses[h].name
+				return st
+			}
+		}
+	}
+	return st
+}
+
+// A hidden unique rectangle has a corner with just a and b. If the row and
+// column through the opposite corner only have a in the rectangle, that
+// corner being b would force the other two to a and the first corner to b:
+// a deadly pattern. So it can't be b.
+func hiddenUniqueRectangle(sud *SudokuSquare, step StepFunc) (bool, error) {
+	if !sud.geo.allowsDeadlyPatterns() {
+		return false, nil
+	}
+	n := sud.size()
+	changes := false
+	err := forEachRectangle(sud, func(ur uniqueRectangle) error {
+		for i, idx := range ur.corners {
+			if sud.cellAt(idx).candidates != ur.mask {
+				continue
+			}
+			opposite := ur.corners[(i+2)%4]
+			row, col := opposite/n, n+opposite%n
+			// the corners sharing the opposite corner's row and column
+			inRow, inCol := ur.corners[(i+1)%4], ur.corners[(i+3)%4]
+			if inRow/n != opposite/n {
+				inRow, inCol = inCol, inRow
+			}
+			st := ur.step(sud, "Hidden Unique Rectangle")
+			for val := 1; val <= n; val++ {
+				if ur.mask&(1<<val) == 0 || !onlyIn(sud, row, val, opposite, inRow) || !onlyIn(sud, col, val, opposite, inCol) {
+					continue
+				}
+				st.eliminate(sud.cellAt(opposite), bits.TrailingZeros32(ur.mask&^(1<<val)))
+			}
+			if len(st.Eliminations) > 0 {
+				changes = true
+				if e := report(step, st); e != nil {
+					return e
+				}
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// Bivalue Universal Grave: if every unsolved cell had just two candidates,
+// and every candidate were left in two places in each house, the values
+// could be swapped round in pairs for a second solution. With only one cell
+// of three candidates the one of them that's left in three places in its
+// houses has to go there.
+func bugPlusOne(sud *SudokuSquare, step StepFunc) (bool, error) {
+	if !sud.geo.allowsDeadlyPatterns() {
+		return false, nil
+	}
+	n := sud.size()
+	extra := -1
+	for idx := 0; idx < n*n; idx++ {
+		cell := sud.cellAt(idx)
+		if cell.isSet {
+			continue
+		}
+		switch bits.OnesCount32(cell.candidates) {
+		case 2:
+		case 3:
+			if extra >= 0 {
+				return false, nil
+			}
+			extra = idx
+		default:
+			return false, nil
+		}
+	}
+	if extra < 0 {
+		return false, nil
+	}
+	cell := sud.cellAt(extra)
+	for _, val := range cellDigits(cell) {
+		if !isBugWithout(sud, extra, val) {
+			continue
+		}
+		st := Step{Technique: "BUG+1"}
+		st.addCell(cell)
+		if err := st.place(sud, cell, val); err != nil {
+			return false, err
+		}
+		return true, report(step, st)
+	}
+	return false, nil
+}
+
+// Whether taking every candidate but val out of the cell at extra would
+// leave each candidate in each house in no place or two.
+func isBugWithout(sud *SudokuSquare, extra, val int) bool {
+	for _, h := range sud.geo.houses {
+		for v := 1; v <= sud.size(); v++ {
+			count := 0
+			for _, idx := range h.cells {
+				if sud.cellAt(idx).hasCandidate(v) && (idx != extra || v != val) {
+					count++
+				}
+			}
+			if count != 0 && count != 2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// The houses two cells are both in.
+func sharedHouses(geo *geometry, a, b int) []int {
+	var shared []int
+	for _, h := range geo.cellHouses[a] {
+		if containsInt(geo.cellHouses[b], h) {
+			shared = append(shared, h)
+		}
+	}
+	return shared
+}
+
+// Whether val is left in house h in no cells but a and b.
+func onlyIn(sud *SudokuSquare, h, val, a, b int) bool {
+	for _, idx := range sud.geo.houses[h].cells {
+		if idx != a && idx != b && sud.cellAt(idx).hasCandidate(val) {
+			return false
+		}
+	}
+	return true
+}
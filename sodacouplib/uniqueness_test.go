@@ -0,0 +1,142 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func candidatesOf(vals ...int) uint32 {
+	var mask uint32
+	for _, val := range vals {
+		mask |= 1 << val
+	}
+	return mask
+}
+
+func TestUniqueRectangle(t *testing.T) {
+	corners := []CellRef{{0, 0}, {0, 3}, {1, 3}, {1, 0}}
+
+	t.Run("type 1", func(t *testing.T) {
+		s := newEmptySudoku()
+		s.cells[0][0].candidates = candidatesOf(1, 2)
+		s.cells[0][3].candidates = candidatesOf(1, 2)
+		s.cells[1][0].candidates = candidatesOf(1, 2)
+		s.cells[1][3].candidates = candidatesOf(1, 2, 5)
+
+		assert.Equal(t, []Step{{
+			Technique:    "Unique Rectangle Type 1",
+			Cells:        corners,
+			Eliminations: []Candidate{{1, 3, 1}, {1, 3, 2}},
+		}}, collectSteps(t, uniqueRectangles, s))
+		noOpCheck(t, uniqueRectangles, s)
+	})
+	t.Run("type 2", func(t *testing.T) {
+		s := newEmptySudoku()
+		s.cells[0][0].candidates = candidatesOf(1, 2)
+		s.cells[1][0].candidates = candidatesOf(1, 2)
+		s.cells[0][3].candidates = candidatesOf(1, 2, 5)
+		s.cells[1][3].candidates = candidatesOf(1, 2, 5)
+
+		steps := collectSteps(t, uniqueRectangles, s)
+		assert.Equal(t, 1, len(steps))
+		assert.Equal(t, "Unique Rectangle Type 2", steps[0].Technique)
+		// the rest of column 3 and block 0 1
+		assert.Equal(t, 7+6, len(steps[0].Eliminations))
+		assert.Contains(t, steps[0].Eliminations, Candidate{8, 3, 5})
+		assert.Contains(t, steps[0].Eliminations, Candidate{2, 5, 5})
+	})
+	t.Run("type 3", func(t *testing.T) {
+		s := newEmptySudoku()
+		s.cells[0][0].candidates = candidatesOf(1, 2)
+		s.cells[1][0].candidates = candidatesOf(1, 2)
+		s.cells[0][3].candidates = candidatesOf(1, 2, 5)
+		s.cells[1][3].candidates = candidatesOf(1, 2, 6)
+		s.cells[5][3].candidates = candidatesOf(5, 6)
+
+		steps := collectSteps(t, uniqueRectangles, s)
+		assert.Equal(t, "Unique Rectangle Type 3", steps[0].Technique)
+		assert.Equal(t, "column 3", steps[0].House)
+		assert.Equal(t, append(corners, CellRef{5, 3}), steps[0].Cells)
+		assert.Equal(t, 6*2, len(steps[0].Eliminations))
+		assert.Equal(t, candidatesOf(1, 2, 5), s.cells[0][3].candidates)
+		assert.Equal(t, candidatesOf(5, 6), s.cells[5][3].candidates)
+	})
+	t.Run("type 4", func(t *testing.T) {
+		s := newEmptySudoku()
+		s.cells[0][0].candidates = candidatesOf(1, 2)
+		s.cells[1][0].candidates = candidatesOf(1, 2)
+		s.cells[0][3].candidates = candidatesOf(1, 2, 5)
+		s.cells[1][3].candidates = candidatesOf(1, 2, 6)
+		keepOnly(s, 1, colRefs(3), CellRef{0, 3}, CellRef{1, 3})
+
+		assert.Equal(t, []Step{{
+			Technique:    "Unique Rectangle Type 4",
+			House:        "column 3",
+			Cells:        corners,
+			Eliminations: []Candidate{{0, 3, 2}, {1, 3, 2}},
+		}}, collectSteps(t, uniqueRectangles, s))
+	})
+	t.Run("not in killer sudoku", func(t *testing.T) {
+		s := newEmptySudokuWithGeometry(newGeometry(ClassicLayout, Variant{
+			Cages: []Cage{{Sum: 3, Cells: []CellRef{{0, 0}, {0, 1}}}},
+		}))
+		s.cells[0][0].candidates = candidatesOf(1, 2)
+		s.cells[0][3].candidates = candidatesOf(1, 2)
+		s.cells[1][0].candidates = candidatesOf(1, 2)
+		s.cells[1][3].candidates = candidatesOf(1, 2, 5)
+		noOpCheck(t, uniqueRectangles, s)
+	})
+}
+
+func TestHiddenUniqueRectangle(t *testing.T) {
+	s := newEmptySudoku()
+	s.cells[0][0].candidates = candidatesOf(1, 2)
+	keepOnly(s, 1, rowRefs(1), CellRef{1, 0}, CellRef{1, 3})
+	keepOnly(s, 1, colRefs(3), CellRef{0, 3}, CellRef{1, 3})
+
+	assert.Equal(t, []Step{{
+		Technique:    "Hidden Unique Rectangle",
+		Cells:        []CellRef{{0, 0}, {0, 3}, {1, 3}, {1, 0}},
+		Eliminations: []Candidate{{1, 3, 2}},
+	}}, collectSteps(t, hiddenUniqueRectangle, s))
+}
+
+func TestBugPlusOne(t *testing.T) {
+	// every cell could be from either of two solutions, the deadly pattern,
+	// bar one with an extra candidate
+	s := newEmptySudokuWithLayout(Layout{2, 2})
+	a := [][]int{{1, 2, 3, 4}, {3, 4, 1, 2}, {2, 1, 4, 3}, {4, 3, 2, 1}}
+	b := [][]int{{3, 4, 1, 2}, {1, 2, 3, 4}, {4, 3, 2, 1}, {2, 1, 4, 3}}
+	for r := range a {
+		for c := range a[r] {
+			s.cells[r][c].candidates = candidatesOf(a[r][c], b[r][c])
+		}
+	}
+	noOpCheck(t, bugPlusOne, s)
+
+	s.cells[0][0].candidates = candidatesOf(1, 2, 3)
+	assert.Equal(t, []Step{{
+		Technique:  "BUG+1",
+		Cells:      []CellRef{{0, 0}},
+		Placements: []Candidate{{0, 0, 2}},
+	}}, collectSteps(t, bugPlusOne, s))
+}
+
+func TestAssumeUnique(t *testing.T) {
+	set := SolveOptions{}.strategies()
+	assert.Equal(t, false, set.IsEnabled("Unique Rectangle"))
+	assert.Equal(t, false, set.IsEnabled("BUG+1"))
+
+	set = SolveOptions{AssumeUnique: true}.strategies()
+	assert.Equal(t, true, set.IsEnabled("Unique Rectangle"))
+	assert.Equal(t, true, set.IsEnabled("Hidden Unique Rectangle"))
+	assert.Equal(t, true, set.IsEnabled("BUG+1"))
+
+	// only turns on the ones already in the set, and leaves the set alone
+	custom, _ := NewStrategySet("Naked Single", "BUG+1")
+	assert.NoError(t, custom.Disable("BUG+1"))
+	set = SolveOptions{Strategies: custom, AssumeUnique: true}.strategies()
+	assert.Equal(t, []string{"Naked Single", "BUG+1"}, set.Names())
+	assert.Equal(t, true, set.IsEnabled("BUG+1"))
+	assert.Equal(t, false, custom.IsEnabled("BUG+1"))
+}
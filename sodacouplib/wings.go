@@ -0,0 +1,82 @@
+package sodacouplib
+
+import (
+	"math/bits"
+)
+
+// A pivot cell with candidates xy sees two pincer cells with xz and yz.
+// Whichever of x or y the pivot turns out to be, one of the pincers has to
+// be z, so z can go from every cell that sees both pincers.
+func xyWing(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return wing(sud, step, "XY-Wing", 2)
+}
+
+// xyWing with a pivot of xyz. The pivot can be z too, so the cells z is
+// removed from have to see the pivot as well as the pincers.
+func xyzWing(sud *SudokuSquare, step StepFunc) (bool, error) {
+	return wing(sud, step, "XYZ-Wing", 3)
+}
+
+func wing(sud *SudokuSquare, step StepFunc, technique string, pivotSize int) (bool, error) {
+	n := sud.size()
+	geo := sud.geo
+	changes := false
+	for p := 0; p < n*n; p++ {
+		pivot := sud.cellAt(p)
+		if pivot.isSet || bits.OnesCount32(pivot.candidates) != pivotSize {
+			continue
+		}
+		peers := geo.peers[p]
+		for i, a := range peers {
+			for _, b := range peers[i+1:] {
+				pincer1, pincer2 := sud.cellAt(a), sud.cellAt(b)
+				z, ok := wingValue(pivot, pincer1, pincer2)
+				if !ok {
+					continue
+				}
+				st := Step{Technique: technique}
+				st.addCell(pivot)
+				st.addCell(pincer1)
+				st.addCell(pincer2)
+				for _, idx := range geo.peers[a] {
+					if idx == b || idx == p || !geo.sees(idx, b) || (pivotSize == 3 && !geo.sees(idx, p)) {
+						continue
+					}
+					st.eliminate(sud.cellAt(idx), z)
+				}
+				if len(st.Eliminations) > 0 {
+					changes = true
+					if e := report(step, st); e != nil {
+						return true, e
+					}
+				}
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Checks the two pincers fit the pivot: both bivalue, sharing one value z
+// the pivot has only if it's an XYZ-Wing, and between them covering the
+// rest of the pivot. Returns z.
+func wingValue(pivot, pincer1, pincer2 *SudokuCell) (int, bool) {
+	if pincer1.isSet || pincer2.isSet {
+		return 0, false
+	}
+	m1, m2 := pincer1.candidates, pincer2.candidates
+	if bits.OnesCount32(m1) != 2 || bits.OnesCount32(m2) != 2 {
+		return 0, false
+	}
+	shared := m1 & m2
+	if bits.OnesCount32(shared) != 1 {
+		return 0, false
+	}
+	// xy + z for an XY-Wing, xyz for an XYZ-Wing
+	if (m1|m2)&^shared != pivot.candidates&^shared || (m1|m2) != pivot.candidates|shared {
+		return 0, false
+	}
+	if bits.OnesCount32(pivot.candidates) == 2 && pivot.candidates&shared != 0 {
+		return 0, false
+	}
+	return bits.TrailingZeros32(shared), true
+}
@@ -0,0 +1,67 @@
+package sodacouplib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestXYWing(t *testing.T) {
+	t.Run("removes the shared value where both pincers are seen", func(t *testing.T) {
+		s := newEmptySudoku()
+		s.cells[0][0].candidates = 1<<1 | 1<<2 // pivot
+		s.cells[0][5].candidates = 1<<1 | 1<<3 // pincer along the row
+		s.cells[4][0].candidates = 1<<2 | 1<<3 // pincer down the column
+
+		var steps []Step
+		_, err := xyWing(s, func(st Step) bool {
+			steps = append(steps, st)
+			return true
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []Step{{
+			Technique:    "XY-Wing",
+			Cells:        []CellRef{{0, 0}, {0, 5}, {4, 0}},
+			Eliminations: []Candidate{{4, 5, 3}},
+		}}, steps)
+		noOpCheck(t, xyWing, s)
+	})
+	t.Run("pincers have to fit the pivot", func(t *testing.T) {
+		s := newEmptySudoku()
+		s.cells[0][0].candidates = 1<<1 | 1<<2
+		s.cells[0][5].candidates = 1<<1 | 1<<3
+		s.cells[4][0].candidates = 1<<1 | 1<<3
+		noOpCheck(t, xyWing, s)
+	})
+}
+
+func TestXYZWing(t *testing.T) {
+	s := newEmptySudoku()
+	s.cells[0][0].candidates = 1<<1 | 1<<2 | 1<<3 // pivot
+	s.cells[0][5].candidates = 1<<1 | 1<<3        // pincer along the row
+	s.cells[1][1].candidates = 1<<2 | 1<<3        // pincer in the box
+	noOpCheck(t, xyWing, s)
+
+	var steps []Step
+	_, err := xyzWing(s, func(st Step) bool {
+		steps = append(steps, st)
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Step{{
+		Technique:    "XYZ-Wing",
+		Cells:        []CellRef{{0, 0}, {0, 5}, {1, 1}},
+		Eliminations: []Candidate{{0, 1, 3}, {0, 2, 3}},
+	}}, steps)
+	assert.Equal(t, true, s.cells[0][0].hasCandidate(3))
+	noOpCheck(t, xyzWing, s)
+}
+
+func TestSees(t *testing.T) {
+	geo := newGeometry(ClassicLayout, Variant{Cages: []Cage{{3, []CellRef{{0, 0}, {4, 4}}}}})
+	assert.Equal(t, true, geo.sees(0, 8))      // same row
+	assert.Equal(t, true, geo.sees(0, 80-8))   // same column
+	assert.Equal(t, true, geo.sees(0, 20))     // same box
+	assert.Equal(t, true, geo.sees(0, 4*9+4))  // same cage
+	assert.Equal(t, false, geo.sees(0, 5*9+5)) // nothing in common
+	assert.Equal(t, 20+1, len(geo.peers[0]))
+}